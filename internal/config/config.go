@@ -4,21 +4,93 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 )
 
+// ChainEntry is one lyrics provider in the configured chain.
+type ChainEntry struct {
+	ID      string
+	Enabled bool
+}
+
+// CustomProvider is a user-defined lyrics provider, read from a
+// [providers.<id>] table. Type selects how it is run:
+// "exec" runs Command with Args, passing the track as JSON on stdin;
+// "http" requests URL and picks the Synced and Plain fields from the JSON.
+type CustomProvider struct {
+	ID      string
+	Type    string
+	Command string
+	Args    []string
+	Timeout int // seconds, 0 for the default
+
+	URL     string
+	Headers []string
+	Synced  string
+	Plain   string
+}
+
 type Config struct {
 	Provider string
 	APIKey   string
 	Model    string
-	AILyrics bool
+
+	// LyricsChain lists lyrics providers in the order they are tried.
+	LyricsChain     []ChainEntry
+	LocalLyricsDir  string
+	CustomProviders []CustomProvider
+
+	// HTTPTimeout bounds the wait for a server to answer in seconds, 0 for
+	// the default.
+	HTTPTimeout int
+	// RateLimits sets the minimum interval between requests to a host in
+	// milliseconds, read from the [rate_limits] table. They override the
+	// built-in limits; 0 lifts a host's limit.
+	RateLimits map[string]int
+	// OfflineOnly restricts lookups to the cache and local files.
+	OfflineOnly bool
+
+	// Endpoints overrides service base URLs by endpoint name; see Endpoint.
+	Endpoints map[string]string
+	// OutputMode is how the openai-compatible provider asks for JSON:
+	// "json_schema", "json_object" (default) or "prompt".
+	OutputMode string
+	// AIFallbacks lists AI providers tried in order after Provider fails,
+	// each as "id" or "id:model".
+	AIFallbacks []string
+	// AIDailyTokens and AIDailyCalls cap AI usage per day across providers,
+	// 0 for no cap.
+	AIDailyTokens int
+	AIDailyCalls  int
+
+	// ParsePrompt, LyricsPrompt, IdentifyPrompt and TranslatePrompt
+	// override the AI prompt templates; empty selects the built-in ones.
+	// {query} stands for the user's query, {language} for the language
+	// lyrics are translated into.
+	ParsePrompt     string
+	LyricsPrompt    string
+	IdentifyPrompt  string
+	TranslatePrompt string
+
+	// TranslateLanguage is the language lyrics are translated into, English
+	// when empty.
+	TranslateLanguage string
 }
 
 func DefaultConfig() *Config {
 	return &Config{
 		Provider: "ollama",
 		Model:    "qwen2.5-coder:14b",
-		AILyrics: true,
+		LyricsChain: []ChainEntry{
+			{ID: "local-lrc", Enabled: true},
+			{ID: "tags", Enabled: true},
+			{ID: "lrclib", Enabled: true},
+			{ID: "genius", Enabled: true},
+			{ID: "ai", Enabled: true},
+		},
+		LocalLyricsDir: filepath.Join(configDir(), "lrc"),
 	}
 }
 
@@ -37,17 +109,47 @@ func Load() *Config {
 	if err != nil {
 		return cfg
 	}
+
+	var disabled []string
+	var custom *CustomProvider
+	inRateLimits := false
 	for _, line := range strings.Split(string(data), "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section := strings.TrimSpace(line[1 : len(line)-1])
+			inRateLimits = section == "rate_limits"
+			if id := strings.TrimPrefix(section, "providers."); id != section && id != "" {
+				cfg.CustomProviders = append(cfg.CustomProviders, CustomProvider{ID: id})
+				custom = &cfg.CustomProviders[len(cfg.CustomProviders)-1]
+			} else {
+				custom = nil
+			}
+			continue
+		}
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
 			continue
 		}
 		key := strings.TrimSpace(parts[0])
-		value := strings.Trim(strings.TrimSpace(parts[1]), "\"")
+		rawValue := strings.TrimSpace(parts[1])
+		value := strings.Trim(rawValue, "\"")
+		if custom != nil {
+			custom.set(key, value, rawValue)
+			continue
+		}
+		if inRateLimits {
+			var ms int
+			if _, err := fmt.Sscanf(value, "%d", &ms); err == nil && ms >= 0 {
+				if cfg.RateLimits == nil {
+					cfg.RateLimits = map[string]int{}
+				}
+				cfg.RateLimits[strings.Trim(key, "\"")] = ms
+			}
+			continue
+		}
 		switch key {
 		case "provider":
 			cfg.Provider = value
@@ -56,7 +158,69 @@ func Load() *Config {
 		case "model":
 			cfg.Model = value
 		case "ai_lyrics":
-			cfg.AILyrics = value == "true"
+			// pre-chain configs toggled AI lyrics on their own
+			if value != "true" {
+				disabled = append(disabled, "ai")
+			}
+		case "lyrics_chain":
+			cfg.LyricsChain = nil
+			for _, id := range splitList(value) {
+				cfg.LyricsChain = append(cfg.LyricsChain, ChainEntry{ID: id, Enabled: true})
+			}
+		case "lyrics_disabled":
+			disabled = append(disabled, splitList(value)...)
+		case "local_lyrics_dir":
+			if value != "" {
+				cfg.LocalLyricsDir = value
+			}
+		case "http_timeout":
+			fmt.Sscanf(value, "%d", &cfg.HTTPTimeout)
+		case "offline_only":
+			cfg.OfflineOnly = value == "true"
+		case "output_mode":
+			cfg.OutputMode = value
+		case "ai_daily_tokens":
+			fmt.Sscanf(value, "%d", &cfg.AIDailyTokens)
+		case "ai_daily_calls":
+			fmt.Sscanf(value, "%d", &cfg.AIDailyCalls)
+		case "ai_fallbacks":
+			cfg.AIFallbacks = splitList(value)
+		case "parse_prompt":
+			cfg.ParsePrompt = unquote(rawValue)
+		case "lyrics_prompt":
+			cfg.LyricsPrompt = unquote(rawValue)
+		case "identify_prompt":
+			cfg.IdentifyPrompt = unquote(rawValue)
+		case "translate_prompt":
+			cfg.TranslatePrompt = unquote(rawValue)
+		case "translate_language":
+			cfg.TranslateLanguage = value
+		default:
+			if name, ok := endpointForKey(key); ok && value != "" {
+				if cfg.Endpoints == nil {
+					cfg.Endpoints = map[string]string{}
+				}
+				cfg.Endpoints[name] = value
+			}
+		}
+	}
+
+	// custom providers missing from the chain are tried last
+	for _, cp := range cfg.CustomProviders {
+		found := false
+		for _, entry := range cfg.LyricsChain {
+			found = found || entry.ID == cp.ID
+		}
+		if !found {
+			cfg.LyricsChain = append(cfg.LyricsChain, ChainEntry{ID: cp.ID, Enabled: true})
+		}
+	}
+
+	for i := range cfg.LyricsChain {
+		for _, id := range disabled {
+			if cfg.LyricsChain[i].ID == id {
+				cfg.LyricsChain[i].Enabled = false
+			}
 		}
 	}
 	return cfg
@@ -67,7 +231,188 @@ func (c *Config) Save() error {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
-	content := fmt.Sprintf("provider = \"%s\"\napi_key = \"%s\"\nmodel = \"%s\"\nai_lyrics = %t\n",
-		c.Provider, c.APIKey, c.Model, c.AILyrics)
+
+	var chain, disabled []string
+	for _, entry := range c.LyricsChain {
+		chain = append(chain, entry.ID)
+		if !entry.Enabled {
+			disabled = append(disabled, entry.ID)
+		}
+	}
+
+	content := fmt.Sprintf("provider = \"%s\"\napi_key = \"%s\"\nmodel = \"%s\"\nlyrics_chain = \"%s\"\nlyrics_disabled = \"%s\"\nlocal_lyrics_dir = \"%s\"\n",
+		c.Provider, c.APIKey, c.Model, strings.Join(chain, ", "), strings.Join(disabled, ", "), c.LocalLyricsDir)
+	if c.HTTPTimeout > 0 {
+		content += fmt.Sprintf("http_timeout = %d\n", c.HTTPTimeout)
+	}
+	if c.OfflineOnly {
+		content += "offline_only = true\n"
+	}
+	if c.OutputMode != "" {
+		content += fmt.Sprintf("output_mode = \"%s\"\n", c.OutputMode)
+	}
+	if len(c.AIFallbacks) > 0 {
+		content += fmt.Sprintf("ai_fallbacks = \"%s\"\n", strings.Join(c.AIFallbacks, ", "))
+	}
+	if c.AIDailyTokens > 0 {
+		content += fmt.Sprintf("ai_daily_tokens = %d\n", c.AIDailyTokens)
+	}
+	if c.AIDailyCalls > 0 {
+		content += fmt.Sprintf("ai_daily_calls = %d\n", c.AIDailyCalls)
+	}
+	if c.ParsePrompt != "" {
+		content += fmt.Sprintf("parse_prompt = %q\n", c.ParsePrompt)
+	}
+	if c.LyricsPrompt != "" {
+		content += fmt.Sprintf("lyrics_prompt = %q\n", c.LyricsPrompt)
+	}
+	if c.IdentifyPrompt != "" {
+		content += fmt.Sprintf("identify_prompt = %q\n", c.IdentifyPrompt)
+	}
+	if c.TranslatePrompt != "" {
+		content += fmt.Sprintf("translate_prompt = %q\n", c.TranslatePrompt)
+	}
+	if c.TranslateLanguage != "" {
+		content += fmt.Sprintf("translate_language = \"%s\"\n", c.TranslateLanguage)
+	}
+	for _, name := range []string{EndpointLRCLIB, EndpointGenius, EndpointOpenAI, EndpointGemini, EndpointOllama, EndpointAnthropic, EndpointOpenAICompatible} {
+		if base := c.Endpoints[name]; base != "" {
+			content += fmt.Sprintf("%s = \"%s\"\n", endpoints[name].key, base)
+		}
+	}
+	if len(c.RateLimits) > 0 {
+		hosts := make([]string, 0, len(c.RateLimits))
+		for host := range c.RateLimits {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		content += "\n[rate_limits]\n"
+		for _, host := range hosts {
+			content += fmt.Sprintf("%q = %d\n", host, c.RateLimits[host])
+		}
+	}
+	for _, cp := range c.CustomProviders {
+		content += "\n" + cp.String()
+	}
 	return os.WriteFile(configPath(), []byte(content), 0644)
 }
+
+func (cp *CustomProvider) set(key, value, rawValue string) {
+	switch key {
+	case "type":
+		cp.Type = value
+	case "command":
+		cp.Command = value
+	case "args":
+		cp.Args = parseStringArray(rawValue)
+	case "timeout":
+		fmt.Sscanf(value, "%d", &cp.Timeout)
+	case "url":
+		cp.URL = value
+	case "headers":
+		cp.Headers = parseStringArray(rawValue)
+	case "synced":
+		cp.Synced = value
+	case "plain":
+		cp.Plain = value
+	}
+}
+
+// String renders the provider as a TOML table.
+func (cp CustomProvider) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[providers.%s]\ntype = %q\n", cp.ID, cp.Type)
+	if cp.Command != "" {
+		fmt.Fprintf(&sb, "command = %q\n", cp.Command)
+	}
+	if len(cp.Args) > 0 {
+		fmt.Fprintf(&sb, "args = %s\n", formatStringArray(cp.Args))
+	}
+	if cp.Timeout > 0 {
+		fmt.Fprintf(&sb, "timeout = %d\n", cp.Timeout)
+	}
+	if cp.URL != "" {
+		fmt.Fprintf(&sb, "url = %q\n", cp.URL)
+	}
+	if len(cp.Headers) > 0 {
+		fmt.Fprintf(&sb, "headers = %s\n", formatStringArray(cp.Headers))
+	}
+	if cp.Synced != "" {
+		fmt.Fprintf(&sb, "synced = %q\n", cp.Synced)
+	}
+	if cp.Plain != "" {
+		fmt.Fprintf(&sb, "plain = %q\n", cp.Plain)
+	}
+	return sb.String()
+}
+
+// EnabledProviders returns the IDs of the enabled lyrics providers in chain order.
+func (c *Config) EnabledProviders() []string {
+	var ids []string
+	for _, entry := range c.LyricsChain {
+		if entry.Enabled {
+			ids = append(ids, entry.ID)
+		}
+	}
+	return ids
+}
+
+// parseStringArray parses a TOML array of strings like ["a", "b"].
+func parseStringArray(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, "[") || !strings.HasSuffix(raw, "]") {
+		if raw = strings.Trim(raw, "\""); raw != "" {
+			return []string{raw}
+		}
+		return nil
+	}
+
+	var items []string
+	var current strings.Builder
+	inString := false
+	escaped := false
+	for _, r := range raw[1 : len(raw)-1] {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"':
+			if inString {
+				items = append(items, current.String())
+				current.Reset()
+			}
+			inString = !inString
+		case inString:
+			current.WriteRune(r)
+		}
+	}
+	return items
+}
+
+func formatStringArray(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = fmt.Sprintf("%q", item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// unquote reads a TOML basic string, keeping escapes such as \n.
+func unquote(raw string) string {
+	if s, err := strconv.Unquote(raw); err == nil {
+		return s
+	}
+	return strings.Trim(raw, "\"")
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
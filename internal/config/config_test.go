@@ -0,0 +1,44 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRateLimits(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := os.MkdirAll(configDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `provider = "ollama"
+
+[rate_limits]
+"lyrics.example.org" = 250
+lrclib.net = 0
+"bad.example.org" = "soon"
+
+[providers.mine]
+type = "http"
+url = "https://lyrics.example.org/{artist}"
+`
+	if err := os.WriteFile(configPath(), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Load()
+	want := map[string]int{"lyrics.example.org": 250, "lrclib.net": 0}
+	if !reflect.DeepEqual(cfg.RateLimits, want) {
+		t.Errorf("RateLimits = %v, want %v", cfg.RateLimits, want)
+	}
+	if len(cfg.CustomProviders) != 1 || cfg.CustomProviders[0].URL == "" {
+		t.Errorf("provider table after [rate_limits] not read: %+v", cfg.CustomProviders)
+	}
+
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if saved := Load(); !reflect.DeepEqual(saved.RateLimits, want) {
+		t.Errorf("RateLimits after saving = %v, want %v", saved.RateLimits, want)
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// Services whose base URL can be changed, e.g. to point at a mirror, a
+// local OpenAI-compatible server or a test stand-in.
+const (
+	EndpointLRCLIB    = "lrclib"
+	EndpointGenius    = "genius"
+	EndpointOpenAI    = "openai"
+	EndpointGemini    = "gemini"
+	EndpointOllama    = "ollama"
+	EndpointAnthropic = "anthropic"
+
+	EndpointOpenAICompatible = "openai-compatible"
+)
+
+type endpoint struct {
+	key    string // config key
+	envVar string
+	def    string
+}
+
+var endpoints = map[string]endpoint{
+	EndpointLRCLIB:    {"lrclib_url", "LRCLIB_URL", "https://lrclib.net"},
+	EndpointGenius:    {"genius_url", "GENIUS_API_URL", "https://api.genius.com"},
+	EndpointOpenAI:    {"openai_base_url", "OPENAI_BASE_URL", "https://api.openai.com/v1"},
+	EndpointGemini:    {"gemini_base_url", "GEMINI_BASE_URL", "https://generativelanguage.googleapis.com/v1beta"},
+	EndpointOllama:    {"ollama_host", "OLLAMA_HOST", "http://localhost:11434"},
+	EndpointAnthropic: {"anthropic_base_url", "ANTHROPIC_BASE_URL", "https://api.anthropic.com/v1"},
+
+	EndpointOpenAICompatible: {"openai_compatible_url", "OPENAI_COMPATIBLE_BASE_URL", "http://localhost:8000/v1"},
+}
+
+// Endpoint returns the base URL of a service, without a trailing slash:
+// the config value if set, then its environment variable, then the
+// public default. Hosts given without a scheme (OLLAMA_HOST=0.0.0.0:11434)
+// get http://.
+func (c *Config) Endpoint(name string) string {
+	e := endpoints[name]
+	base := c.Endpoints[name]
+	if base == "" {
+		base = os.Getenv(e.envVar)
+	}
+	if base == "" {
+		base = e.def
+	}
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// endpointForKey maps a config key such as "ollama_host" to its endpoint.
+func endpointForKey(key string) (string, bool) {
+	for name, e := range endpoints {
+		if e.key == key {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,308 @@
+// Package httpclient provides the HTTP client shared by every network
+// provider: a User-Agent, retries with jittered backoff honoring
+// Retry-After, per-host rate limits and tracking of whether we are offline.
+//
+// The client sets no overall timeout, which would cut off streamed AI
+// responses; callers bound their requests with a context deadline.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrOffline is wrapped by errors of requests that never reached the server.
+var ErrOffline = errors.New("offline")
+
+// Options configures a Client. Zero values select the defaults.
+type Options struct {
+	// Timeout bounds the wait for a response's headers, per attempt. Reading
+	// the body is bounded only by the request's context. Defaults to 60s.
+	Timeout time.Duration
+	// UserAgent is sent with requests that don't set their own.
+	UserAgent string
+	// Retries is the number of extra attempts after a network error, a 429
+	// or a 5xx gateway error. Only idempotent requests are retried, and
+	// requests made with AllowRetry only on a 429 or 503 with Retry-After.
+	// Defaults to 2; negative disables retries.
+	Retries int
+	// RateLimits maps a host to the minimum interval between requests to it.
+	RateLimits map[string]time.Duration
+}
+
+// DefaultRateLimits keeps us within what the public lyric APIs ask for.
+var DefaultRateLimits = map[string]time.Duration{
+	"lrclib.net":     500 * time.Millisecond,
+	"api.genius.com": 200 * time.Millisecond,
+	"genius.com":     500 * time.Millisecond,
+}
+
+const (
+	baseBackoff   = 500 * time.Millisecond
+	maxRetryAfter = 30 * time.Second
+)
+
+// Client is an *http.Client whose transport adds the shared behavior.
+// Pass Client.Client to provider constructors.
+type Client struct {
+	*http.Client
+	transport *transport
+}
+
+// New creates a Client.
+func New(opts Options) *Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 60 * time.Second
+	}
+	if opts.Retries == 0 {
+		opts.Retries = 2
+	}
+	if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	if opts.RateLimits == nil {
+		opts.RateLimits = DefaultRateLimits
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.ResponseHeaderTimeout = opts.Timeout
+
+	t := &transport{
+		base:       base,
+		userAgent:  opts.UserAgent,
+		retries:    opts.Retries,
+		rateLimits: opts.RateLimits,
+		next:       map[string]time.Time{},
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	return &Client{
+		Client:    &http.Client{Transport: t},
+		transport: t,
+	}
+}
+
+type allowRetryKey struct{}
+
+// AllowRetry marks requests made with ctx as safe to send again when the
+// server turns them away with a 429 or 503 and a Retry-After header, which
+// means it did not act on them. Use it for POSTs such as AI generations;
+// idempotent requests are always retried.
+func AllowRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allowRetryKey{}, true)
+}
+
+// Offline reports whether the last request failed to reach its server.
+// It flips back as soon as any request gets a response.
+func (c *Client) Offline() bool {
+	c.transport.mu.Lock()
+	defer c.transport.mu.Unlock()
+	return c.transport.offline
+}
+
+type transport struct {
+	base       http.RoundTripper
+	userAgent  string
+	retries    int
+	rateLimits map[string]time.Duration
+
+	mu      sync.Mutex
+	next    map[string]time.Time // earliest time of the next request per host
+	offline bool
+	rand    *rand.Rand
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.waitTurn(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			if req.Context().Err() != nil {
+				return nil, err
+			}
+			if isNetworkError(err) && !isLoopback(req.URL.Hostname()) {
+				t.setOffline(true)
+				err = fmt.Errorf("%w: %v", ErrOffline, err)
+			}
+			retry, ok := rewind(req)
+			if attempt >= t.retries || !idempotent(req.Method) || !ok {
+				return nil, err
+			}
+			if err := sleep(req.Context(), t.backoff(attempt)); err != nil {
+				return nil, err
+			}
+			req = retry
+			continue
+		}
+
+		t.setOffline(false)
+		if !retryableStatus(resp.StatusCode) || attempt >= t.retries {
+			return resp, nil
+		}
+
+		delay := t.backoff(attempt)
+		ra, hasRetryAfter := retryAfter(resp.Header.Get("Retry-After"))
+		if hasRetryAfter {
+			if ra > maxRetryAfter {
+				return resp, nil
+			}
+			delay = ra
+		}
+		if !idempotent(req.Method) && !(hasRetryAfter && retryAllowed(req, resp.StatusCode)) {
+			return resp, nil
+		}
+		retry, ok := rewind(req)
+		if !ok {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		req = retry
+	}
+}
+
+// waitTurn blocks until the per-host rate limit lets the next request go.
+func (t *transport) waitTurn(ctx context.Context, host string) error {
+	interval, ok := t.rateLimits[host]
+	if !ok {
+		return nil
+	}
+
+	t.mu.Lock()
+	now := time.Now()
+	at := t.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	t.next[host] = at.Add(interval)
+	t.mu.Unlock()
+
+	return sleep(ctx, at.Sub(now))
+}
+
+func (t *transport) setOffline(offline bool) {
+	t.mu.Lock()
+	t.offline = offline
+	t.mu.Unlock()
+}
+
+// rewind copies req with a fresh body for another attempt, reporting
+// whether that is possible.
+func rewind(req *http.Request) (*http.Request, bool) {
+	retry := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return retry, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	retry.Body = body
+	return retry, true
+}
+
+// idempotent reports whether sending a request with method twice has the
+// same effect as sending it once.
+func idempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryAllowed reports whether a non-idempotent request opted in with
+// AllowRetry and was refused with a status saying it was not processed.
+func retryAllowed(req *http.Request, code int) bool {
+	allowed, _ := req.Context().Value(allowRetryKey{}).(bool)
+	return allowed && (code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable)
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isNetworkError reports whether err means the server could not be reached
+// at all, as opposed to a slow or misbehaving server.
+func isNetworkError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isLoopback reports whether host is this machine; a local server such as
+// Ollama being down says nothing about connectivity.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		d := time.Until(at)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// backoff doubles the delay each attempt and picks a random point in its
+// upper half so clients don't retry in lockstep.
+func (t *transport) backoff(attempt int) time.Duration {
+	d := baseBackoff << attempt
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return d/2 + time.Duration(t.rand.Int63n(int64(d/2)+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
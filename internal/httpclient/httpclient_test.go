@@ -0,0 +1,115 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer answers every request with status and headers after
+// counting it.
+func countingServer(t *testing.T, status int, header map[string]string) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		for k, v := range header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetries(t *testing.T) {
+	retryNow := map[string]string{"Retry-After": "0"}
+	tests := []struct {
+		name       string
+		method     string
+		allowRetry bool
+		status     int
+		header     map[string]string
+		wantCalls  int32
+	}{
+		{"get on bad gateway", http.MethodGet, false, http.StatusBadGateway, nil, 3},
+		{"get on not found", http.MethodGet, false, http.StatusNotFound, nil, 1},
+		{"post on bad gateway", http.MethodPost, false, http.StatusBadGateway, nil, 1},
+		{"post on 429 without opting in", http.MethodPost, false, http.StatusTooManyRequests, retryNow, 1},
+		{"opted-in post on 429", http.MethodPost, true, http.StatusTooManyRequests, retryNow, 3},
+		{"opted-in post on 503", http.MethodPost, true, http.StatusServiceUnavailable, retryNow, 3},
+		{"opted-in post on 429 without Retry-After", http.MethodPost, true, http.StatusTooManyRequests, nil, 1},
+		{"opted-in post on bad gateway", http.MethodPost, true, http.StatusBadGateway, retryNow, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := countingServer(t, tt.status, tt.header)
+			client := New(Options{RateLimits: map[string]time.Duration{}})
+
+			ctx := context.Background()
+			if tt.allowRetry {
+				ctx = AllowRetry(ctx)
+			}
+			req, err := http.NewRequestWithContext(ctx, tt.method, srv.URL, strings.NewReader("{}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := atomic.LoadInt32(calls); got != tt.wantCalls {
+				t.Errorf("server saw %d requests, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestTimeoutSparesStreamedBodies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			io.WriteString(w, "chunk\n")
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	client := New(Options{Timeout: 50 * time.Millisecond})
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("streamed body cut off: %v", err)
+	}
+	if got := strings.Count(string(body), "chunk"); got != 3 {
+		t.Errorf("read %d chunks, want 3", got)
+	}
+}
+
+func TestTimeoutBoundsSlowHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	client := New(Options{Timeout: 50 * time.Millisecond, Retries: -1})
+	if resp, err := client.Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected a timeout waiting for headers")
+	}
+}
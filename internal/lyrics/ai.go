@@ -0,0 +1,120 @@
+package lyrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AIClient is the part of an AI backend the ai provider needs;
+// parse.Provider satisfies it.
+type AIClient interface {
+	FetchLyrics(ctx context.Context, query string) (artist, title, lyrics string, err error)
+}
+
+// AIStreamer is implemented by AI backends that can stream their answer;
+// parse.LyricsStreamer satisfies it.
+type AIStreamer interface {
+	StreamLyrics(ctx context.Context, query string, onPartial func(artist, title, lyrics string)) (artist, title, lyrics string, err error)
+}
+
+// AIModelClient is implemented by AI backends that answer with one of
+// several models; parse.FallbackProvider satisfies it. Its methods also
+// return the model that answered.
+type AIModelClient interface {
+	FetchLyricsByModel(ctx context.Context, query string) (artist, title, lyrics, model string, err error)
+	StreamLyricsByModel(ctx context.Context, query string, onPartial func(artist, title, lyrics string)) (artist, title, lyrics, model string, err error)
+}
+
+// AIProvider asks an AI backend for plain lyrics. Its timestamps are
+// unknown, so it never returns synced lyrics.
+type AIProvider struct {
+	client AIClient
+}
+
+// NewAIProvider creates a lyrics provider backed by an AI client.
+func NewAIProvider(client AIClient) *AIProvider {
+	return &AIProvider{client: client}
+}
+
+// Timeout allows for slow models generating a whole song.
+func (p *AIProvider) Timeout() time.Duration {
+	return 60 * time.Second
+}
+
+// Fallback keeps paid AI calls out of the race; the ai provider only runs
+// once every other provider has come back empty.
+func (p *AIProvider) Fallback() bool {
+	return true
+}
+
+// Confidence is low: models can make lyrics up.
+func (p *AIProvider) Confidence() float64 {
+	return 0.3
+}
+
+// FetchLyrics asks the AI backend for the lyrics of a song.
+func (p *AIProvider) FetchLyrics(ctx context.Context, track Track) (string, error) {
+	_, _, lyrics, _, err := p.ask(ctx, track, nil)
+	return lyrics, err
+}
+
+// FetchSong asks the AI backend for the lyrics of a song, reporting them
+// as the backend writes them when the lookup wants progress. The song
+// records the model that wrote them and the song it took the track for.
+func (p *AIProvider) FetchSong(ctx context.Context, track Track) (*Song, error) {
+	var onPartial func(artist, title, lyrics string)
+	if progress := progressFrom(ctx); progress != nil {
+		onPartial = func(artist, title, lyrics string) {
+			progress(Partial{Artist: artist, Title: title, Lyrics: lyrics, Source: ProviderAI})
+		}
+	}
+	artist, title, lyrics, model, err := p.ask(ctx, track, onPartial)
+	if err != nil {
+		return nil, err
+	}
+	return &Song{
+		Artist:           track.Artist,
+		Title:            track.Title,
+		Lyrics:           lyrics,
+		Source:           ProviderAI,
+		Model:            model,
+		IdentifiedArtist: artist,
+		IdentifiedTitle:  title,
+	}, nil
+}
+
+// ask puts the track to the backend, streaming when onPartial is set and
+// the backend can stream. It returns the song the backend identified, its
+// lyrics and the model that wrote them.
+func (p *AIProvider) ask(ctx context.Context, track Track, onPartial func(artist, title, lyrics string)) (artist, title, lyrics, model string, err error) {
+	query := track.Artist + " " + track.Title
+	if client, ok := p.client.(AIModelClient); ok {
+		if onPartial != nil {
+			artist, title, lyrics, model, err = client.StreamLyricsByModel(ctx, query, onPartial)
+		} else {
+			artist, title, lyrics, model, err = client.FetchLyricsByModel(ctx, query)
+		}
+	} else {
+		if streamer, ok := p.client.(AIStreamer); ok && onPartial != nil {
+			artist, title, lyrics, err = streamer.StreamLyrics(ctx, query, onPartial)
+		} else {
+			artist, title, lyrics, err = p.client.FetchLyrics(ctx, query)
+		}
+		if m, ok := p.client.(interface{ Model() string }); ok {
+			model = m.Model()
+		}
+	}
+	if err != nil {
+		return "", "", "", "", err
+	}
+	if lyrics == "" {
+		return "", "", "", "", fmt.Errorf("ai returned no lyrics")
+	}
+	return artist, title, lyrics, model, nil
+}
+
+// FetchSynced is not supported by AI backends.
+func (p *AIProvider) FetchSynced(ctx context.Context, track Track) ([]Line, error) {
+	return nil, fmt.Errorf("ai does not provide synced lyrics")
+}
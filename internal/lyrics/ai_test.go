@@ -0,0 +1,88 @@
+package lyrics
+
+import (
+	"context"
+	"testing"
+)
+
+// stubAIClient is a single AI backend answering with lyrics naming the
+// query.
+type stubAIClient struct{}
+
+func (c *stubAIClient) Model() string { return "fixed" }
+
+func (c *stubAIClient) FetchLyrics(ctx context.Context, query string) (string, string, string, error) {
+	return "Identified Artist", "Identified Title", "lyrics for " + query, nil
+}
+
+func (c *stubAIClient) StreamLyrics(ctx context.Context, query string, onPartial func(artist, title, lyrics string)) (string, string, string, error) {
+	onPartial("Identified Artist", "", "")
+	return c.FetchLyrics(ctx, query)
+}
+
+// modelAIClient falls back between models, answering with model(query).
+type modelAIClient struct {
+	stubAIClient
+	model func(query string) string
+}
+
+func (c *modelAIClient) FetchLyricsByModel(ctx context.Context, query string) (string, string, string, string, error) {
+	artist, title, lyrics, err := c.FetchLyrics(ctx, query)
+	return artist, title, lyrics, c.model(query), err
+}
+
+func (c *modelAIClient) StreamLyricsByModel(ctx context.Context, query string, onPartial func(artist, title, lyrics string)) (string, string, string, string, error) {
+	artist, title, lyrics, err := c.StreamLyrics(ctx, query, onPartial)
+	return artist, title, lyrics, c.model(query), err
+}
+
+func TestAIProviderRecordsAnsweringModel(t *testing.T) {
+	byQuery := &modelAIClient{model: func(query string) string {
+		if query == "Known Song" {
+			return "small"
+		}
+		return "large"
+	}}
+	tests := []struct {
+		name      string
+		client    AIClient
+		track     Track
+		stream    bool
+		wantModel string
+	}{
+		{"first model", byQuery, Track{Artist: "Known", Title: "Song"}, false, "small"},
+		{"fallback model", byQuery, Track{Artist: "Other", Title: "Song"}, false, "large"},
+		{"streamed fallback model", byQuery, Track{Artist: "Other", Title: "Song"}, true, "large"},
+		{"single backend", &stubAIClient{}, Track{Artist: "Known", Title: "Song"}, true, "fixed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var partials []Partial
+			if tt.stream {
+				ctx = WithProgress(ctx, func(p Partial) { partials = append(partials, p) })
+			}
+
+			song, err := NewAIProvider(tt.client).FetchSong(ctx, tt.track)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if song.Model != tt.wantModel || song.Source != ProviderAI {
+				t.Errorf("song by %q from %q, want %q from ai", song.Model, song.Source, tt.wantModel)
+			}
+			if song.Lyrics != "lyrics for "+tt.track.Artist+" "+tt.track.Title {
+				t.Errorf("lyrics = %q", song.Lyrics)
+			}
+			if song.Artist != tt.track.Artist || song.IdentifiedArtist != "Identified Artist" || song.IdentifiedTitle != "Identified Title" {
+				t.Errorf("song %q identified as %q - %q", song.Artist, song.IdentifiedArtist, song.IdentifiedTitle)
+			}
+			if tt.stream && (len(partials) != 1 || partials[0].Source != ProviderAI) {
+				t.Errorf("partials = %+v, want one from ai", partials)
+			}
+			if !tt.stream && len(partials) != 0 {
+				t.Errorf("reported partials %+v without a progress function", partials)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package lyrics
+
+import (
+	"sort"
+	"strings"
+)
+
+// MapAnnotations places annotations on the lines of plain lyrics by matching
+// their fragments. Annotations whose fragment can't be found are dropped.
+func MapAnnotations(text string, annotations []Annotation) []Annotation {
+	lines := strings.Split(text, "\n")
+	norm := make([]string, len(lines))
+	for i, line := range lines {
+		norm[i] = normalizeLyric(line)
+	}
+
+	var mapped []Annotation
+	for _, ann := range annotations {
+		var fragment []string
+		for _, f := range strings.Split(ann.Fragment, "\n") {
+			if n := normalizeLyric(f); n != "" {
+				fragment = append(fragment, n)
+			}
+		}
+		if len(fragment) == 0 {
+			continue
+		}
+
+		start, end := findFragment(norm, fragment)
+		if start < 0 {
+			continue
+		}
+		ann.StartLine = start
+		ann.EndLine = end
+		mapped = append(mapped, ann)
+	}
+
+	sort.SliceStable(mapped, func(i, j int) bool {
+		return mapped[i].StartLine < mapped[j].StartLine
+	})
+	return mapped
+}
+
+// AnnotationsForLine returns the annotations covering a line, in lyrics order.
+func AnnotationsForLine(annotations []Annotation, line int) []Annotation {
+	var result []Annotation
+	for _, ann := range annotations {
+		if line >= ann.StartLine && line <= ann.EndLine {
+			result = append(result, ann)
+		}
+	}
+	return result
+}
+
+// PlainLines returns, for each synced line, the line of plain lyrics it
+// shows, -1 when the plain lyrics don't have it. Lines are matched in
+// order, so a repeated chorus maps to its own repeat, and section headers
+// and lines the two versions don't share are skipped.
+func PlainLines(plain string, synced []Line) []int {
+	lines := strings.Split(plain, "\n")
+	norm := make([]string, len(lines))
+	for i, line := range lines {
+		norm[i] = normalizeLyric(line)
+	}
+
+	result := make([]int, len(synced))
+	next := 0
+	for k, line := range synced {
+		result[k] = -1
+		want := normalizeLyric(line.Text)
+		if want == "" {
+			continue
+		}
+		for j := next; j < len(norm); j++ {
+			if norm[j] == want {
+				result[k] = j
+				next = j + 1
+				break
+			}
+		}
+	}
+	return result
+}
+
+// findFragment returns the first and last line covered by a fragment.
+// Blank lyric lines inside the fragment are skipped. A fragment of whole
+// lines wins over partial matches, in which the first fragment line may
+// end a lyric line and the last one start one, on word boundaries.
+func findFragment(lines, fragment []string) (int, int) {
+	for _, whole := range []bool{true, false} {
+		if start, end := matchFragment(lines, fragment, whole); start >= 0 {
+			return start, end
+		}
+	}
+	return -1, -1
+}
+
+func matchFragment(lines, fragment []string, whole bool) (int, int) {
+	last := len(fragment) - 1
+	for i := range lines {
+		if lines[i] == "" || !fragmentLine(lines[i], fragment[0], true, last == 0, whole) {
+			continue
+		}
+
+		end := i
+		matched := 1
+		for j := i + 1; j < len(lines) && matched <= last; j++ {
+			if lines[j] == "" {
+				continue
+			}
+			if !fragmentLine(lines[j], fragment[matched], false, matched == last, whole) {
+				break
+			}
+			end = j
+			matched++
+		}
+		if matched > last {
+			return i, end
+		}
+	}
+	return -1, -1
+}
+
+// fragmentLine reports whether a normalized lyric line matches a line of a
+// fragment: all of it, or unless whole is set, its end for the fragment's
+// first line and its start for the last. A one-line fragment may be
+// anywhere in the line. Partial matches only end at word boundaries, so
+// "love" doesn't match "lovely".
+func fragmentLine(line, fragment string, first, last, whole bool) bool {
+	if line == fragment {
+		return true
+	}
+	if whole || (!first && !last) {
+		return false
+	}
+	line, fragment = " "+line+" ", " "+fragment+" "
+	switch {
+	case first && last:
+		return strings.Contains(line, fragment)
+	case first:
+		return strings.HasSuffix(line, fragment)
+	default:
+		return strings.HasPrefix(line, fragment)
+	}
+}
+
+func normalizeLyric(s string) string {
+	s = strings.ToLower(s)
+	s = nonWordRegex.ReplaceAllString(s, " ")
+	return strings.TrimSpace(s)
+}
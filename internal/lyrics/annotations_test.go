@@ -0,0 +1,76 @@
+package lyrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlainLines(t *testing.T) {
+	plain := "[Verse 1]\nHello, world\nSecond line\n\n[Chorus]\nLa la la\nOh oh\n\n[Chorus]\nLa la la\nOh oh"
+	synced := []Line{
+		{Text: "Hello world"},
+		{Text: "Second line"},
+		{Text: ""},
+		{Text: "La la la"},
+		{Text: "Oh oh"},
+		{Text: "Ad-lib only in the synced version"},
+		{Text: "La la la"},
+		{Text: "Oh oh"},
+	}
+	want := []int{1, 2, -1, 5, 6, -1, 9, 10}
+	if got := PlainLines(plain, synced); !reflect.DeepEqual(got, want) {
+		t.Errorf("PlainLines() = %v, want %v", got, want)
+	}
+	if got := PlainLines(plain, nil); len(got) != 0 {
+		t.Errorf("PlainLines(nil) = %v, want none", got)
+	}
+}
+
+func TestMapAnnotations(t *testing.T) {
+	plain := "[Verse 1]\nHello, world\nSecond line\n\nThird line"
+	annotations := MapAnnotations(plain, []Annotation{
+		{Fragment: "Second line\nThird line", Body: "spans a blank line"},
+		{Fragment: "hello", Body: "partial line"},
+		{Fragment: "not in the song", Body: "dropped"},
+	})
+	if len(annotations) != 2 {
+		t.Fatalf("MapAnnotations() = %+v, want 2 annotations", annotations)
+	}
+	if a := annotations[0]; a.StartLine != 1 || a.EndLine != 1 {
+		t.Errorf("partial line annotation on %d-%d, want 1-1", a.StartLine, a.EndLine)
+	}
+	if a := annotations[1]; a.StartLine != 2 || a.EndLine != 4 {
+		t.Errorf("multi-line annotation on %d-%d, want 2-4", a.StartLine, a.EndLine)
+	}
+	if got := AnnotationsForLine(annotations, 3); len(got) != 1 {
+		t.Errorf("AnnotationsForLine(3) = %+v, want the multi-line annotation", got)
+	}
+}
+
+func TestMapAnnotationsWordBoundaries(t *testing.T) {
+	plain := "What a lovely day\nOh no, not again\nI love you\nOh\nEnd of the line\nStart of the next"
+	tests := []struct {
+		fragment string
+		start    int
+		end      int
+	}{
+		{"love", 2, 2},
+		{"Oh", 3, 3},
+		{"not again", 1, 1},
+		{"the line\nStart of", 4, 5},
+		{"lovely", 0, 0},
+		{"ove", -1, -1},
+		{"the lin\nStart of", -1, -1},
+		{"of the line\nthe next", -1, -1},
+	}
+	for _, tt := range tests {
+		mapped := MapAnnotations(plain, []Annotation{{Fragment: tt.fragment}})
+		start, end := -1, -1
+		if len(mapped) == 1 {
+			start, end = mapped[0].StartLine, mapped[0].EndLine
+		}
+		if start != tt.start || end != tt.end {
+			t.Errorf("fragment %q on %d-%d, want %d-%d", tt.fragment, start, end, tt.start, tt.end)
+		}
+	}
+}
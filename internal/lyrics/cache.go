@@ -66,6 +66,20 @@ func (c *Cache) UpdateOffset(artist, title string, offset float64) error {
 	return c.Save(cached)
 }
 
+// UpdateTranslation stores the translation of a cached song into language.
+func (c *Cache) UpdateTranslation(artist, title, language string, lines []string) error {
+	cached, err := c.Load(artist, title)
+	if err != nil {
+		return err
+	}
+
+	if cached.Translations == nil {
+		cached.Translations = map[string][]string{}
+	}
+	cached.Translations[language] = lines
+	return c.Save(cached)
+}
+
 func (c *Cache) cachePath(artist, title string) string {
 	safeArtist := sanitizeFilename(artist)
 	safeTitle := sanitizeFilename(title)
@@ -117,7 +131,14 @@ func (c *Cache) ListAll() []CachedSongEntry {
 		if err := json.Unmarshal(data, &cached); err != nil {
 			continue
 		}
-		songs = append(songs, CachedSongEntry{Artist: cached.Artist, Title: cached.Title})
+		songs = append(songs, CachedSongEntry{
+			Artist:     cached.Artist,
+			Title:      cached.Title,
+			Source:     cached.Source,
+			Model:      cached.Model,
+			Synced:     cached.HasSyncedLyrics,
+			Confidence: cached.Confidence,
+		})
 	}
 	return songs
 }
@@ -0,0 +1,191 @@
+package lyrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExecProvider runs an external executable for every lookup. The executable
+// receives an ExecRequest as JSON on stdin and answers with an ExecResponse
+// as JSON on stdout. Anything written to stderr is kept for the debug view.
+type ExecProvider struct {
+	command string
+	args    []string
+	timeout time.Duration
+
+	mu         sync.Mutex
+	lastStderr string
+}
+
+// ExecRequest is the JSON document an exec provider reads from stdin.
+type ExecRequest struct {
+	Artist   string  `json:"artist"`
+	Title    string  `json:"title"`
+	Album    string  `json:"album,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	FilePath string  `json:"file_path,omitempty"`
+}
+
+// ExecResponse is the JSON document an exec provider writes to stdout.
+// Either Plain or Lines must be set; Source optionally labels where the
+// lyrics came from.
+type ExecResponse struct {
+	Plain  string     `json:"plain,omitempty"`
+	Lines  []ExecLine `json:"lines,omitempty"`
+	Source string     `json:"source,omitempty"`
+}
+
+// ExecLine is a timed line in an ExecResponse, time in seconds.
+type ExecLine struct {
+	Time float64 `json:"time"`
+	Text string  `json:"text"`
+}
+
+// NewExecProvider creates a provider running command with args. A zero
+// timeout defaults to 10 seconds.
+func NewExecProvider(command string, args []string, timeout time.Duration) *ExecProvider {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &ExecProvider{command: command, args: args, timeout: timeout}
+}
+
+// FetchLyrics runs the executable and returns its plain lyrics.
+func (p *ExecProvider) FetchLyrics(ctx context.Context, track Track) (string, error) {
+	song, err := p.FetchSong(ctx, track)
+	if err != nil {
+		return "", err
+	}
+	if song.Lyrics == "" {
+		return "", fmt.Errorf("%s returned no plain lyrics", p.command)
+	}
+	return song.Lyrics, nil
+}
+
+// FetchSynced runs the executable and returns its timed lines.
+func (p *ExecProvider) FetchSynced(ctx context.Context, track Track) ([]Line, error) {
+	song, err := p.FetchSong(ctx, track)
+	if err != nil {
+		return nil, err
+	}
+	if !song.HasSyncedLyrics {
+		return nil, fmt.Errorf("%s returned no synced lyrics", p.command)
+	}
+	return song.SyncedLyrics, nil
+}
+
+// FetchSong runs the executable once and returns whatever it found.
+func (p *ExecProvider) FetchSong(ctx context.Context, track Track) (*Song, error) {
+	resp, err := p.run(ctx, track)
+	if err != nil {
+		return nil, err
+	}
+
+	song := &Song{
+		Artist: track.Artist,
+		Title:  track.Title,
+		Lyrics: strings.TrimSpace(resp.Plain),
+		Source: resp.Source,
+	}
+	for _, l := range resp.Lines {
+		song.SyncedLyrics = append(song.SyncedLyrics, Line{Timestamp: l.Time, Text: l.Text})
+	}
+	song.HasSyncedLyrics = len(song.SyncedLyrics) > 0
+
+	if song.Lyrics == "" && !song.HasSyncedLyrics {
+		return nil, fmt.Errorf("%s found no lyrics", p.command)
+	}
+	return song, nil
+}
+
+// Timeout returns how long a single run may take.
+func (p *ExecProvider) Timeout() time.Duration {
+	return p.timeout
+}
+
+// Diagnostics returns the stderr output of the last run.
+func (p *ExecProvider) Diagnostics() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastStderr
+}
+
+func (p *ExecProvider) run(ctx context.Context, track Track) (*ExecResponse, error) {
+	input, err := json.Marshal(ExecRequest{
+		Artist:   track.Artist,
+		Title:    track.Title,
+		Album:    track.Album,
+		Duration: track.Duration,
+		FilePath: track.FilePath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal exec request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command, p.args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+
+	p.mu.Lock()
+	p.lastStderr = strings.TrimSpace(stderr.String())
+	p.mu.Unlock()
+
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return nil, fmt.Errorf("%s timed out after %s", p.command, p.timeout)
+	case context.Canceled:
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", p.command, err)
+	}
+
+	var resp ExecResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse %s output: %w", p.command, err)
+	}
+	return &resp, nil
+}
+
+// CheckExecProvider runs an exec provider once for track and verifies its
+// output follows the protocol. It is meant for plugin authors and returns
+// every problem found; an empty result means the provider conforms.
+func CheckExecProvider(ctx context.Context, command string, args []string, track Track) []string {
+	p := NewExecProvider(command, args, 0)
+	resp, err := p.run(ctx, track)
+	if err != nil {
+		problems := []string{err.Error()}
+		if stderr := p.Diagnostics(); stderr != "" {
+			problems = append(problems, "stderr: "+stderr)
+		}
+		return problems
+	}
+
+	var problems []string
+	if strings.TrimSpace(resp.Plain) == "" && len(resp.Lines) == 0 {
+		problems = append(problems, `response has neither "plain" nor "lines"`)
+	}
+	if !sort.SliceIsSorted(resp.Lines, func(i, j int) bool { return resp.Lines[i].Time < resp.Lines[j].Time }) {
+		problems = append(problems, `"lines" are not sorted by time`)
+	}
+	for i, l := range resp.Lines {
+		if l.Time < 0 {
+			problems = append(problems, fmt.Sprintf("line %d has a negative time", i+1))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,130 @@
+package lyrics
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeScript writes a shell script provider into dir and returns its path.
+func writeScript(t *testing.T, dir, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, "provider.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckExecProvider(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("script providers need a POSIX shell")
+	}
+
+	tests := []struct {
+		name    string
+		script  string
+		timeout time.Duration
+		want    []string // substrings of the problems, in order; none for a pass
+	}{
+		{
+			name:   "plain lyrics",
+			script: `cat > /dev/null; printf '%s\n' '{"plain": "Hello\nworld", "source": "archive"}'`,
+		},
+		{
+			name:   "timed lines",
+			script: `cat > /dev/null; echo '{"lines": [{"time": 1.5, "text": "One"}, {"time": 3, "text": "Two"}]}'`,
+		},
+		{
+			name:   "bad json",
+			script: `cat > /dev/null; echo 'lyrics: none'`,
+			want:   []string{"failed to parse"},
+		},
+		{
+			name:   "non-zero exit",
+			script: `cat > /dev/null; echo 'archive offline' >&2; exit 3`,
+			want:   []string{"exit status 3", "stderr: archive offline"},
+		},
+		{
+			name:    "timeout",
+			script:  `exec sleep 5`,
+			timeout: 200 * time.Millisecond,
+			want:    []string{"timed out"},
+		},
+		{
+			name:   "empty response",
+			script: `cat > /dev/null; echo '{"source": "archive"}'`,
+			want:   []string{`neither "plain" nor "lines"`},
+		},
+		{
+			name:   "unsorted and negative times",
+			script: `cat > /dev/null; echo '{"lines": [{"time": 2, "text": "B"}, {"time": -1, "text": "A"}]}'`,
+			want:   []string{"not sorted", "line 2 has a negative time"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			script := writeScript(t, dir, tt.script)
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			problems := CheckExecProvider(ctx, script, nil, Track{Artist: "A", Title: "T", Duration: 180})
+			if tt.timeout > 0 && time.Since(start) > 2*time.Second {
+				t.Errorf("CheckExecProvider took %s, the provider was not stopped", time.Since(start))
+			}
+
+			if len(problems) != len(tt.want) {
+				t.Fatalf("CheckExecProvider() = %q, want %d problems", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %d = %q, want it to mention %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestExecProviderRequest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("script providers need a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	script := writeScript(t, dir, `cat > "`+dir+`/request.json"; echo '{"lines": [{"time": 1, "text": "One"}], "source": "archive"}'`)
+	p := NewExecProvider(script, nil, time.Second)
+	track := Track{Artist: "Artist", Title: "Title", Album: "Album", Duration: 180, FilePath: "/music/song.flac"}
+
+	song, err := p.FetchSong(context.Background(), track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !song.HasSyncedLyrics || song.SyncedLyrics[0].Text != "One" || song.Source != "archive" {
+		t.Errorf("FetchSong() = %+v", song)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "request.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req ExecRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatal(err)
+	}
+	want := ExecRequest{Artist: "Artist", Title: "Title", Album: "Album", Duration: 180, FilePath: "/music/song.flac"}
+	if req != want {
+		t.Errorf("request = %+v, want %+v", req, want)
+	}
+}
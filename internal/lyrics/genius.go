@@ -1,128 +1,299 @@
 package lyrics
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"regexp"
 	"strings"
+
+	"golang.org/x/net/html"
 )
 
 // GeniusProvider fetches lyrics from Genius.com.
 type GeniusProvider struct {
 	accessToken string
+	baseURL     string
 	client      *http.Client
 }
 
-// NewGeniusProvider creates a new Genius lyrics provider.
-func NewGeniusProvider(accessToken string) *GeniusProvider {
+// NewGeniusProvider creates a new Genius lyrics provider using the API at
+// baseURL, normally https://api.genius.com.
+func NewGeniusProvider(accessToken, baseURL string, client *http.Client) *GeniusProvider {
 	return &GeniusProvider{
 		accessToken: accessToken,
-		client:      &http.Client{},
+		baseURL:     baseURL,
+		client:      client,
 	}
 }
 
 type geniusSearchResponse struct {
 	Response struct {
 		Hits []struct {
-			Result struct {
-				Title         string `json:"title"`
-				PrimaryArtist struct {
-					Name string `json:"name"`
-				} `json:"primary_artist"`
-				URL string `json:"url"`
-			} `json:"result"`
+			Result geniusHit `json:"result"`
 		} `json:"hits"`
 	} `json:"response"`
 }
 
+type geniusHit struct {
+	ID            int    `json:"id"`
+	Title         string `json:"title"`
+	ArtistNames   string `json:"artist_names"`
+	PrimaryArtist struct {
+		Name string `json:"name"`
+	} `json:"primary_artist"`
+	URL string `json:"url"`
+}
+
 // FetchLyrics retrieves plain text lyrics from Genius by searching and scraping.
-func (p *GeniusProvider) FetchLyrics(artist, title string) (string, error) {
+func (p *GeniusProvider) FetchLyrics(ctx context.Context, track Track) (string, error) {
+	song, err := p.FetchSong(ctx, track)
+	if err != nil {
+		return "", err
+	}
+	return song.Lyrics, nil
+}
+
+// FetchSong searches Genius, scrapes the best matching page and returns the
+// lyrics together with the Genius song ID used for annotations.
+func (p *GeniusProvider) FetchSong(ctx context.Context, track Track) (*Song, error) {
+	artist, title := track.Artist, track.Title
 	query := fmt.Sprintf("%s %s", artist, title)
-	searchURL := fmt.Sprintf("https://api.genius.com/search?q=%s", url.QueryEscape(query))
+	searchURL := fmt.Sprintf("%s/search?q=%s", p.baseURL, url.QueryEscape(query))
+
+	var searchResp geniusSearchResponse
+	if err := p.getJSON(ctx, searchURL, &searchResp); err != nil {
+		return nil, err
+	}
+
+	if len(searchResp.Response.Hits) == 0 {
+		return nil, fmt.Errorf("song not found on genius")
+	}
+
+	hits := make([]geniusHit, 0, len(searchResp.Response.Hits))
+	for _, h := range searchResp.Response.Hits {
+		hits = append(hits, h.Result)
+	}
+	best, score, ok := bestGeniusHit(hits, artist, title)
+	if !ok {
+		return nil, fmt.Errorf("no confident match on genius for %s - %s", artist, title)
+	}
+
+	sections, err := p.scrapeLyrics(ctx, best.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape lyrics: %w", err)
+	}
+
+	return &Song{
+		Artist:     artist,
+		Title:      title,
+		Lyrics:     JoinSections(sections),
+		Sections:   sections,
+		GeniusID:   best.ID,
+		Confidence: score,
+	}, nil
+}
+
+type geniusReferentsResponse struct {
+	Response struct {
+		Referents []struct {
+			Fragment    string `json:"fragment"`
+			Annotations []struct {
+				Body struct {
+					Plain string `json:"plain"`
+				} `json:"body"`
+			} `json:"annotations"`
+		} `json:"referents"`
+	} `json:"response"`
+}
+
+// FetchAnnotations retrieves the annotations attached to a Genius song.
+// Line ranges are left unset; use MapAnnotations to place them.
+func (p *GeniusProvider) FetchAnnotations(ctx context.Context, songID int) ([]Annotation, error) {
+	var annotations []Annotation
+	for page := 1; page <= 5; page++ {
+		apiURL := fmt.Sprintf("%s/referents?song_id=%d&text_format=plain&per_page=50&page=%d", p.baseURL, songID, page)
+
+		var refResp geniusReferentsResponse
+		if err := p.getJSON(ctx, apiURL, &refResp); err != nil {
+			return nil, err
+		}
+
+		for _, ref := range refResp.Response.Referents {
+			for _, ann := range ref.Annotations {
+				body := strings.TrimSpace(ann.Body.Plain)
+				if body == "" {
+					continue
+				}
+				annotations = append(annotations, Annotation{
+					Fragment: strings.TrimSpace(ref.Fragment),
+					Body:     body,
+				})
+			}
+		}
+
+		if len(refResp.Response.Referents) < 50 {
+			break
+		}
+	}
+	return annotations, nil
+}
 
-	req, err := http.NewRequest("GET", searchURL, nil)
+func (p *GeniusProvider) getJSON(ctx context.Context, apiURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create genius request: %w", err)
+		return fmt.Errorf("failed to create genius request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+p.accessToken)
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("genius request failed: %w", err)
+		return fmt.Errorf("genius request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read genius response: %w", err)
+		return fmt.Errorf("failed to read genius response: %w", err)
 	}
 
-	var searchResp geniusSearchResponse
-	if err := json.Unmarshal(body, &searchResp); err != nil {
-		return "", fmt.Errorf("failed to parse genius response: %w", err)
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("genius returned status %d", resp.StatusCode)
 	}
 
-	if len(searchResp.Response.Hits) == 0 {
-		return "", fmt.Errorf("song not found on genius")
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse genius response: %w", err)
 	}
-
-	songURL := searchResp.Response.Hits[0].Result.URL
-
-	lyrics, err := p.scrapeLyrics(songURL)
-	if err != nil {
-		return "", fmt.Errorf("failed to scrape lyrics: %w", err)
-	}
-
-	return lyrics, nil
+	return nil
 }
 
 // FetchSynced is not supported by Genius (only plain text).
-func (p *GeniusProvider) FetchSynced(artist, title string) ([]Line, error) {
+func (p *GeniusProvider) FetchSynced(ctx context.Context, track Track) ([]Line, error) {
 	return nil, fmt.Errorf("genius does not provide synced lyrics")
 }
 
-func (p *GeniusProvider) scrapeLyrics(songURL string) (string, error) {
-	resp, err := p.client.Get(songURL)
+// scrapeLyrics fetches a Genius song page and returns its lyrics by section.
+func (p *GeniusProvider) scrapeLyrics(ctx context.Context, songURL string) ([]Section, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", songURL, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("genius returned status %d", resp.StatusCode)
 	}
 
-	html := string(body)
+	return parseGeniusLyrics(resp.Body)
+}
 
-	re := regexp.MustCompile(`<div[^>]*data-lyrics-container="true"[^>]*>(.*?)</div>`)
-	matches := re.FindAllStringSubmatch(html, -1)
+// parseGeniusLyrics walks every data-lyrics-container subtree of a Genius
+// song page and returns its lyrics split into sections. Nested markup such
+// as annotation links and inline ads is flattened, and elements Genius marks
+// with data-exclude-from-selection (contributor counts, ad slots) are skipped.
+func parseGeniusLyrics(r io.Reader) ([]Section, error) {
+	z := html.NewTokenizer(r)
 
-	if len(matches) == 0 {
-		return "", fmt.Errorf("lyrics not found in page")
-	}
+	var text strings.Builder
+	found := false
+	containerDepth := 0 // open <div>s inside the current lyrics container
+	skipTag := ""
+	skipDepth := 0
 
-	var lyrics []string
-	for _, match := range matches {
-		if len(match) > 1 {
-			lyricHTML := match[1]
-			lyricHTML = regexp.MustCompile(`<br[^>]*>`).ReplaceAllString(lyricHTML, "\n")
-			lyricHTML = regexp.MustCompile(`<[^>]+>`).ReplaceAllString(lyricHTML, "")
-			lyricHTML = strings.ReplaceAll(lyricHTML, "&amp;", "&")
-			lyricHTML = strings.ReplaceAll(lyricHTML, "&quot;", "\"")
-			lyricHTML = strings.ReplaceAll(lyricHTML, "&#x27;", "'")
-			lyricHTML = strings.ReplaceAll(lyricHTML, "&lt;", "<")
-			lyricHTML = strings.ReplaceAll(lyricHTML, "&gt;", ">")
-			lyrics = append(lyrics, lyricHTML)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse genius page: %w", z.Err())
+		}
+
+		token := z.Token()
+
+		if containerDepth == 0 {
+			if tt == html.StartTagToken && token.Data == "div" && geniusAttr(token, "data-lyrics-container") == "true" {
+				found = true
+				containerDepth = 1
+			}
+			continue
+		}
+
+		if skipTag != "" {
+			switch {
+			case tt == html.StartTagToken && token.Data == skipTag:
+				skipDepth++
+			case tt == html.EndTagToken && token.Data == skipTag:
+				skipDepth--
+				if skipDepth == 0 {
+					skipTag = ""
+				}
+			}
+			if token.Data == "div" {
+				containerDepth += divDelta(tt)
+			}
+			continue
+		}
+
+		switch tt {
+		case html.StartTagToken:
+			switch {
+			case token.Data == "script" || token.Data == "style" || geniusAttr(token, "data-exclude-from-selection") == "true":
+				skipTag = token.Data
+				skipDepth = 1
+			case token.Data == "br":
+				text.WriteString("\n")
+			}
+		case html.SelfClosingTagToken:
+			if token.Data == "br" {
+				text.WriteString("\n")
+			}
+		case html.TextToken:
+			text.WriteString(token.Data)
+		}
+
+		if token.Data == "div" {
+			// block boundaries inside the container are line breaks
+			containerDepth += divDelta(tt)
+			if !strings.HasSuffix(text.String(), "\n") {
+				text.WriteString("\n")
+			}
 		}
 	}
 
-	if len(lyrics) == 0 {
-		return "", fmt.Errorf("no lyrics extracted")
+	if !found {
+		return nil, fmt.Errorf("lyrics not found in page")
 	}
 
-	return strings.TrimSpace(strings.Join(lyrics, "\n\n")), nil
+	sections := SplitSections(text.String())
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("no lyrics extracted")
+	}
+	return sections, nil
+}
+
+func geniusAttr(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func divDelta(tt html.TokenType) int {
+	switch tt {
+	case html.StartTagToken:
+		return 1
+	case html.EndTagToken:
+		return -1
+	}
+	return 0
 }
@@ -0,0 +1,103 @@
+package lyrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGeniusLyrics(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []Section
+		wantErr bool
+	}{
+		{
+			fixture: "genius_nested.html",
+			want: []Section{
+				{Header: "Verse 1", Lines: []string{
+					"First line with an annotation",
+					"Second line & more — été",
+					"Third line after an ad",
+				}},
+				{Header: "Chorus: Singer", Lines: []string{
+					`Chorus "quoted" line`,
+					"Don't stop",
+				}},
+				{Header: "Verse 2", Lines: []string{
+					"Second container",
+					"Last line",
+				}},
+			},
+		},
+		{
+			fixture: "genius_unlabelled.html",
+			want: []Section{
+				{Lines: []string{"Just a line", "Another line"}},
+				{Lines: []string{"After a gap"}},
+			},
+		},
+		{fixture: "genius_missing.html", wantErr: true},
+		{fixture: "genius_empty.html", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			f, err := os.Open("testdata/" + tt.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			got, err := parseGeniusLyrics(f)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseGeniusLyrics() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseGeniusLyrics() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinSectionsRoundTrip(t *testing.T) {
+	text := "[Intro]\nOh\n\n[Verse 1: Artist]\nLine one\nLine two\n\nUnlabelled"
+	if got := JoinSections(SplitSections(text)); got != text {
+		t.Errorf("JoinSections(SplitSections(%q)) = %q", text, got)
+	}
+}
+
+func TestScrapeLyricsStatus(t *testing.T) {
+	page, err := os.ReadFile("testdata/genius_nested.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok-lyrics" {
+			// Cloudflare and missing pages come with lyrics-like markup too
+			w.WriteHeader(http.StatusForbidden)
+		}
+		w.Write(page)
+	}))
+	defer srv.Close()
+
+	p := NewGeniusProvider("token", srv.URL, srv.Client())
+	sections, err := p.scrapeLyrics(context.Background(), srv.URL+"/ok-lyrics")
+	if err != nil || len(sections) == 0 {
+		t.Fatalf("scrapeLyrics() = %v, %v; want the page's sections", sections, err)
+	}
+
+	if _, err := p.scrapeLyrics(context.Background(), srv.URL+"/blocked-lyrics"); err == nil || !strings.Contains(err.Error(), "status 403") {
+		t.Errorf("scrapeLyrics() error = %v, want status 403", err)
+	}
+}
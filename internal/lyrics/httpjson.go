@@ -0,0 +1,172 @@
+package lyrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// HTTPJSONProvider fetches lyrics from an HTTP endpoint returning JSON,
+// described entirely by configuration: a URL template, extra headers and
+// selectors for the synced and plain lyrics fields. It suits self-hosted
+// lyric services and LRCLIB mirrors.
+type HTTPJSONProvider struct {
+	urlTemplate string
+	headers     http.Header
+	syncedPath  string
+	plainPath   string
+	client      *http.Client
+}
+
+// NewHTTPJSONProvider creates a provider from a URL template such as
+// "https://mirror/api/get?artist_name={artist}&track_name={title}".
+// Placeholders {artist}, {title}, {album} and {duration} are replaced with
+// the escaped track fields. headers are "Name: value" strings and the
+// selectors are paths like "syncedLyrics" or "$.results[0].lrc"; either
+// selector may be empty.
+func NewHTTPJSONProvider(urlTemplate string, headers []string, syncedPath, plainPath string, client *http.Client) *HTTPJSONProvider {
+	h := http.Header{}
+	for _, header := range headers {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) == 2 {
+			h.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+		}
+	}
+	return &HTTPJSONProvider{
+		urlTemplate: urlTemplate,
+		headers:     h,
+		syncedPath:  syncedPath,
+		plainPath:   plainPath,
+		client:      client,
+	}
+}
+
+// FetchLyrics retrieves plain lyrics from the plain selector.
+func (p *HTTPJSONProvider) FetchLyrics(ctx context.Context, track Track) (string, error) {
+	song, err := p.FetchSong(ctx, track)
+	if err != nil {
+		return "", err
+	}
+	if song.Lyrics == "" {
+		return "", fmt.Errorf("no plain lyrics available")
+	}
+	return song.Lyrics, nil
+}
+
+// FetchSynced retrieves synced lyrics from the synced selector.
+func (p *HTTPJSONProvider) FetchSynced(ctx context.Context, track Track) ([]Line, error) {
+	song, err := p.FetchSong(ctx, track)
+	if err != nil {
+		return nil, err
+	}
+	if !song.HasSyncedLyrics {
+		return nil, fmt.Errorf("no synced lyrics available")
+	}
+	return song.SyncedLyrics, nil
+}
+
+// FetchSong performs a single request and extracts both selectors.
+func (p *HTTPJSONProvider) FetchSong(ctx context.Context, track Track) (*Song, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.expandURL(track), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range p.headers {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	song := &Song{Artist: track.Artist, Title: track.Title}
+	if synced := selectJSONString(doc, p.syncedPath); synced != "" {
+		song.SyncedLyrics = ParseSynced(synced)
+		song.HasSyncedLyrics = len(song.SyncedLyrics) > 0
+	}
+	song.Lyrics = strings.TrimSpace(selectJSONString(doc, p.plainPath))
+
+	if !song.HasSyncedLyrics && song.Lyrics == "" {
+		return nil, fmt.Errorf("no lyrics in response")
+	}
+	return song, nil
+}
+
+// expandURL fills the URL template in for track. Placeholders in the path
+// are escaped as path segments and those in the query as query values, so
+// "AC/DC" or "Simon & Garfunkel" can't change the URL's structure.
+func (p *HTTPJSONProvider) expandURL(track Track) string {
+	duration := ""
+	if track.Duration > 0 {
+		duration = strconv.Itoa(int(track.Duration + 0.5))
+	}
+	expand := func(template string, escape func(string) string) string {
+		return strings.NewReplacer(
+			"{artist}", escape(track.Artist),
+			"{title}", escape(track.Title),
+			"{album}", escape(track.Album),
+			"{duration}", duration,
+		).Replace(template)
+	}
+
+	path, query := p.urlTemplate, ""
+	if i := strings.Index(path, "?"); i >= 0 {
+		path, query = path[:i], path[i:]
+	}
+	return expand(path, url.PathEscape) + expand(query, url.QueryEscape)
+}
+
+// selectJSONString walks a decoded JSON document along a JSONPath-like
+// selector ("$.a.b[0].c", "a.b.0.c") and returns the string found there.
+func selectJSONString(doc interface{}, path string) string {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if path == "" {
+		return ""
+	}
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+
+	current := doc
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			continue
+		}
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return ""
+			}
+			current = node[i]
+		default:
+			return ""
+		}
+	}
+
+	s, _ := current.(string)
+	return s
+}
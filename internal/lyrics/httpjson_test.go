@@ -0,0 +1,138 @@
+package lyrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSelectJSONString(t *testing.T) {
+	var doc interface{}
+	err := json.Unmarshal([]byte(`{
+		"syncedLyrics": "[00:01.00]Hi",
+		"data": {"track": {"lyrics": "nested", "id": 7}},
+		"results": [{"lrc": "first"}, {"lrc": "second"}],
+		"empty": null
+	}`), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"syncedLyrics", "[00:01.00]Hi"},
+		{"$.syncedLyrics", "[00:01.00]Hi"},
+		{"data.track.lyrics", "nested"},
+		{"$.data.track.lyrics", "nested"},
+		{"$.results[1].lrc", "second"},
+		{"results.0.lrc", "first"},
+		{"  $.results[0].lrc  ", "first"},
+		{"", ""},
+		{"$", ""},
+		{"missing", ""},
+		{"data.missing.lyrics", ""},
+		{"results[2].lrc", ""},
+		{"results[-1].lrc", ""},
+		{"results.x.lrc", ""},
+		{"data.track.id", ""},
+		{"data.track", ""},
+		{"empty.value", ""},
+		{"syncedLyrics.more", ""},
+	}
+	for _, tt := range tests {
+		if got := selectJSONString(doc, tt.path); got != tt.want {
+			t.Errorf("selectJSONString(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPJSONExpandURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		track    Track
+		want     string
+	}{
+		{
+			name:     "query",
+			template: "https://mirror/api/get?artist_name={artist}&track_name={title}&duration={duration}",
+			track:    Track{Artist: "Simon & Garfunkel", Title: "Mrs. Robinson", Duration: 243.6},
+			want:     "https://mirror/api/get?artist_name=Simon+%26+Garfunkel&track_name=Mrs.+Robinson&duration=244",
+		},
+		{
+			name:     "reserved characters in the query",
+			template: "https://mirror/get?q={artist}+{title}",
+			track:    Track{Artist: "AC/DC", Title: "What's Up? #1 = 100%"},
+			want:     "https://mirror/get?q=AC%2FDC+What%27s+Up%3F+%231+%3D+100%25",
+		},
+		{
+			name:     "path",
+			template: "https://lyrics.local/{artist}/{title}.json",
+			track:    Track{Artist: "AC/DC", Title: "Back in Black?"},
+			want:     "https://lyrics.local/AC%2FDC/Back%20in%20Black%3F.json",
+		},
+		{
+			name:     "path and query",
+			template: "https://lyrics.local/{artist}?album={album}",
+			track:    Track{Artist: "Sigur Rós", Album: "( )"},
+			want:     "https://lyrics.local/Sigur%20R%C3%B3s?album=%28+%29",
+		},
+		{
+			name:     "unknown duration",
+			template: "https://mirror/get?title={title}&duration={duration}",
+			track:    Track{Title: "Song"},
+			want:     "https://mirror/get?title=Song&duration=",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewHTTPJSONProvider(tt.template, nil, "", "", nil)
+			if got := p.expandURL(tt.track); got != tt.want {
+				t.Errorf("expandURL() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPJSONProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Query().Get("artist") {
+		case "AC/DC":
+			w.Write([]byte(`{"result": {"synced": "[00:01.00]Back in black", "plain": "Back in black"}}`))
+		case "Nobody":
+			w.Write([]byte(`{"result": {}}`))
+		default:
+			w.Write([]byte(`not json`))
+		}
+	}))
+	defer server.Close()
+
+	p := NewHTTPJSONProvider(server.URL+"/get?artist={artist}", []string{"X-Api-Key: secret"}, "$.result.synced", "$.result.plain", server.Client())
+
+	song, err := p.FetchSong(context.Background(), Track{Artist: "AC/DC", Title: "Back in Black"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !song.HasSyncedLyrics || song.SyncedLyrics[0].Text != "Back in black" || song.Lyrics != "Back in black" {
+		t.Errorf("FetchSong() = %+v", song)
+	}
+
+	for _, artist := range []string{"Nobody", "Broken"} {
+		if song, err := p.FetchSong(context.Background(), Track{Artist: artist}); err == nil {
+			t.Errorf("FetchSong(%s) = %+v, want error", artist, song)
+		}
+	}
+
+	unauthorized := NewHTTPJSONProvider(server.URL+"/get?artist={artist}", nil, "$.result.synced", "", server.Client())
+	if _, err := unauthorized.FetchSong(context.Background(), Track{Artist: "AC/DC"}); err == nil {
+		t.Error("FetchSong() without the api key header succeeded")
+	}
+}
@@ -0,0 +1,107 @@
+package lyrics
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalLRCProvider reads lyrics files from a local directory. Files are
+// matched by name, "Artist - Title.lrc" or "Title.lrc", ignoring case and
+// punctuation. .ttml and .vtt files are read as synced lyrics and .txt
+// files as plain lyrics.
+type LocalLRCProvider struct {
+	dir string
+}
+
+// NewLocalLRCProvider creates a provider reading lyrics files from dir.
+func NewLocalLRCProvider(dir string) *LocalLRCProvider {
+	return &LocalLRCProvider{dir: dir}
+}
+
+// Local marks the provider as usable in offline-only mode.
+func (p *LocalLRCProvider) Local() bool {
+	return true
+}
+
+// Confidence is full: the files were put there for the song.
+func (p *LocalLRCProvider) Confidence() float64 {
+	return 1
+}
+
+// FetchLyrics retrieves plain lyrics from a matching .txt file.
+func (p *LocalLRCProvider) FetchLyrics(ctx context.Context, track Track) (string, error) {
+	path, err := p.find(track, ".txt")
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read lyrics file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// FetchSynced retrieves synced lyrics from a matching .lrc, .ttml or .vtt file.
+func (p *LocalLRCProvider) FetchSynced(ctx context.Context, track Track) ([]Line, error) {
+	path, err := p.find(track, ".lrc", ".ttml", ".vtt")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lyrics file: %w", err)
+	}
+
+	lines := ParseSynced(string(data))
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no synced lyrics in %s", filepath.Base(path))
+	}
+	return lines, nil
+}
+
+func (p *LocalLRCProvider) find(track Track, exts ...string) (string, error) {
+	artist, title := track.Artist, track.Title
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		return "", fmt.Errorf("local lyrics dir not readable: %w", err)
+	}
+
+	full := sanitizeFilename(artist + " - " + title)
+	titleOnly := sanitizeFilename(title)
+
+	var titleMatch string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !containsString(exts, ext) {
+			continue
+		}
+
+		name := sanitizeFilename(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		switch name {
+		case full:
+			return filepath.Join(p.dir, entry.Name()), nil
+		case titleOnly:
+			titleMatch = filepath.Join(p.dir, entry.Name())
+		}
+	}
+
+	if titleMatch != "" {
+		return titleMatch, nil
+	}
+	return "", fmt.Errorf("no local lyrics file for %s - %s", artist, title)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,22 +1,27 @@
 package lyrics
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 // LRCLIBProvider fetches synced lyrics from lrclib.net.
 type LRCLIBProvider struct {
-	client *http.Client
+	baseURL string
+	client  *http.Client
 }
 
-// NewLRCLIBProvider creates a new LRCLIB lyrics provider.
-func NewLRCLIBProvider() *LRCLIBProvider {
+// NewLRCLIBProvider creates a new LRCLIB lyrics provider talking to
+// baseURL, https://lrclib.net or a mirror.
+func NewLRCLIBProvider(baseURL string, client *http.Client) *LRCLIBProvider {
 	return &LRCLIBProvider{
-		client: &http.Client{},
+		baseURL: baseURL,
+		client:  client,
 	}
 }
 
@@ -24,18 +29,29 @@ type lrclibResponse struct {
 	SyncedLyrics string `json:"syncedLyrics"`
 }
 
+// Confidence is high, LRCLIB only answers for the exact artist and title.
+func (p *LRCLIBProvider) Confidence() float64 {
+	return 0.9
+}
+
 // FetchLyrics is not supported by LRCLIB (only synced lyrics).
-func (p *LRCLIBProvider) FetchLyrics(artist, title string) (string, error) {
+func (p *LRCLIBProvider) FetchLyrics(ctx context.Context, track Track) (string, error) {
 	return "", fmt.Errorf("lrclib only provides synced lyrics")
 }
 
 // FetchSynced retrieves time-synced lyrics from LRCLIB.
-func (p *LRCLIBProvider) FetchSynced(artist, title string) ([]Line, error) {
-	apiURL := fmt.Sprintf("https://lrclib.net/api/get?artist_name=%s&track_name=%s",
-		url.QueryEscape(artist),
-		url.QueryEscape(title))
+func (p *LRCLIBProvider) FetchSynced(ctx context.Context, track Track) ([]Line, error) {
+	apiURL := fmt.Sprintf("%s/api/get?artist_name=%s&track_name=%s",
+		p.baseURL,
+		url.QueryEscape(track.Artist),
+		url.QueryEscape(track.Title))
 
-	resp, err := p.client.Get(apiURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lrclib request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("lrclib request failed: %w", err)
 	}
@@ -61,3 +77,59 @@ func (p *LRCLIBProvider) FetchSynced(artist, title string) ([]Line, error) {
 
 	return ParseLRC(lrcResp.SyncedLyrics), nil
 }
+
+// Search finds songs through LRCLIB's /api/search. Results whose lyrics
+// contain fragment come first, as the search also matches titles and
+// artists.
+func (p *LRCLIBProvider) Search(ctx context.Context, fragment string) ([]Candidate, error) {
+	apiURL := fmt.Sprintf("%s/api/search?q=%s", p.baseURL, url.QueryEscape(fragment))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lrclib request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("lrclib request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("lrclib returned status %d", resp.StatusCode)
+	}
+
+	var results []struct {
+		TrackName    string `json:"trackName"`
+		ArtistName   string `json:"artistName"`
+		PlainLyrics  string `json:"plainLyrics"`
+		SyncedLyrics string `json:"syncedLyrics"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to parse lrclib search: %w", err)
+	}
+
+	want := normalizeLyric(fragment)
+	var matched, others []Candidate
+	for _, r := range results {
+		lines := strings.Split(r.PlainLyrics, "\n")
+		if r.PlainLyrics == "" {
+			lines = lines[:0]
+			for _, line := range ParseLRC(r.SyncedLyrics) {
+				lines = append(lines, line.Text)
+			}
+		}
+		c := Candidate{Artist: r.ArtistName, Title: r.TrackName, Snippet: matchingLine(lines, fragment), Source: ProviderLRCLIB}
+		if strings.Contains(normalizeLyric(strings.Join(lines, "\n")), want) {
+			matched = appendCandidates(matched, c)
+		} else {
+			others = appendCandidates(others, c)
+		}
+	}
+
+	candidates := appendCandidates(matched, others...)
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+	return candidates, nil
+}
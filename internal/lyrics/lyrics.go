@@ -1,11 +1,46 @@
 package lyrics
 
+import (
+	"context"
+	"strings"
+	"time"
+)
+
 // Line represents a single line of synced lyrics with its timestamp.
+// Words and Agent are only set by formats that carry them (enhanced LRC, TTML).
 type Line struct {
 	Timestamp float64 `json:"timestamp"`
+	End       float64 `json:"end,omitempty"`
+	Text      string  `json:"text"`
+	Words     []Word  `json:"words,omitempty"`
+	Agent     string  `json:"agent,omitempty"`
+}
+
+// Word is a timed fragment of a line. Concatenating the words of a line
+// yields the line text, so trailing spaces are kept on each word.
+type Word struct {
+	Timestamp float64 `json:"timestamp"`
+	End       float64 `json:"end,omitempty"`
 	Text      string  `json:"text"`
 }
 
+// Section is a labelled block of plain lyrics, such as the "[Verse 1]" or
+// "[Chorus]" blocks on Genius. Header is empty for unlabelled blocks.
+type Section struct {
+	Header string   `json:"header,omitempty"`
+	Lines  []string `json:"lines"`
+}
+
+// Track identifies the song to fetch lyrics for. Only Artist and Title are
+// required; the rest helps providers that can make use of it.
+type Track struct {
+	Artist   string
+	Title    string
+	Album    string
+	Duration float64 // seconds, 0 if unknown
+	FilePath string  // local audio file, empty if not playing from disk
+}
+
 // Song contains lyrics information for a song.
 type Song struct {
 	Artist          string
@@ -13,21 +48,101 @@ type Song struct {
 	Lyrics          string
 	SyncedLyrics    []Line
 	HasSyncedLyrics bool
+	GeniusID        int
+	// Sections are the plain lyrics split at their section markers, such as
+	// "[Chorus]", for sources that mark them.
+	Sections []Section
+	// Source is the ID of the provider that produced the lyrics.
+	Source string
+	// Model is the AI model that wrote the lyrics, empty for other sources.
+	Model string
+	// IdentifiedArtist and IdentifiedTitle are the song an AI took the
+	// track for, empty for other sources.
+	IdentifiedArtist string
+	IdentifiedTitle  string
+	// FetchedAt is when the lyrics were found.
+	FetchedAt time.Time
+	// Duration is the track length in seconds the lyrics were matched
+	// against, 0 if unknown.
+	Duration float64
+	// Confidence is how sure the source is that the lyrics belong to the
+	// song, from 0 to 1.
+	Confidence float64
+	// Verification is how AI lyrics compared with lyrics found elsewhere:
+	// Verified, Unverified, or empty until checked. Found lyrics that
+	// replaced AI lyrics are Verified when the AI's were close.
+	Verification string
 }
 
-// Provider defines the interface for lyrics sources.
+// Provider defines the interface for lyrics sources. Implementations must
+// give up and return ctx.Err() once ctx is done.
 type Provider interface {
 	// FetchLyrics retrieves plain text lyrics for a song.
-	FetchLyrics(artist, title string) (string, error)
+	FetchLyrics(ctx context.Context, track Track) (string, error)
 
 	// FetchSynced retrieves time-synced lyrics for a song.
 	// Returns nil slice if synced lyrics are not available.
-	FetchSynced(artist, title string) ([]Line, error)
+	FetchSynced(ctx context.Context, track Track) ([]Line, error)
 }
 
-type CachedSongEntry struct {
+// SongFetcher is implemented by providers that know more about a match than
+// its text, such as Genius and its song ID, or that produce synced and plain
+// lyrics from a single lookup. The chain calls FetchSong once instead of
+// FetchSynced and FetchLyrics.
+type SongFetcher interface {
+	FetchSong(ctx context.Context, track Track) (*Song, error)
+}
+
+// Partial is lyrics still arriving from a streaming provider.
+type Partial struct {
+	Artist string
+	Title  string
+	Lyrics string
+	// Source is the ID of the provider streaming the lyrics.
+	Source string
+}
+
+// StreamingProvider is implemented by providers that can report plain
+// lyrics while they are still being generated. The chain calls
+// StreamLyrics instead of FetchLyrics when the lookup's context carries a
+// progress function; see WithProgress.
+type StreamingProvider interface {
+	StreamLyrics(ctx context.Context, track Track, onPartial func(Partial)) (string, error)
+}
+
+// Candidate is a song a lyric fragment may come from, for the user to pick
+// before its lyrics are fetched.
+type Candidate struct {
 	Artist string
 	Title  string
+	// Snippet is the line the fragment matched, empty when unknown.
+	Snippet string
+	// Source is where the candidate was found: SourceCache or a provider ID.
+	Source string
+}
+
+// Searcher is implemented by providers that can find songs by a fragment of
+// their lyrics. Service.Search asks every searcher in the chain.
+type Searcher interface {
+	Search(ctx context.Context, fragment string) ([]Candidate, error)
+}
+
+// Annotation is a note attached to a fragment of the lyrics. StartLine and
+// EndLine index into the plain lyrics split by newline.
+type Annotation struct {
+	Fragment  string
+	Body      string
+	StartLine int
+	EndLine   int
+}
+
+type CachedSongEntry struct {
+	Artist     string
+	Title      string
+	Source     string
+	Model      string
+	Synced     bool
+	Confidence float64
 }
 
 // CachedSong represents a song stored in cache with offset information.
@@ -38,4 +153,60 @@ type CachedSong struct {
 	SyncedLyrics    []Line  `json:"syncedLyrics"`
 	HasSyncedLyrics bool    `json:"hasSyncedLyrics"`
 	Offset          float64 `json:"offset"`
+	GeniusID        int     `json:"geniusId,omitempty"`
+	Source          string  `json:"source,omitempty"`
+
+	// Sections are the plain lyrics split at their section markers.
+	Sections []Section `json:"sections,omitempty"`
+
+	// Provenance of the lyrics, as on Song: the AI model that wrote them,
+	// when and for which track length they were found, and how sure the
+	// source was of the match. FetchedAt is nil for entries cached before
+	// it was recorded.
+	Model      string     `json:"model,omitempty"`
+	FetchedAt  *time.Time `json:"fetchedAt,omitempty"`
+	Duration   float64    `json:"duration,omitempty"`
+	Confidence float64    `json:"confidence,omitempty"`
+
+	// IdentifiedArtist and IdentifiedTitle are the song an AI took the
+	// track for.
+	IdentifiedArtist string `json:"identifiedArtist,omitempty"`
+	IdentifiedTitle  string `json:"identifiedTitle,omitempty"`
+
+	// Verification is how AI lyrics compared with lyrics found elsewhere.
+	Verification string `json:"verification,omitempty"`
+
+	// Translations are the lyrics translated by language, one entry per
+	// line of the lyrics.
+	Translations map[string][]string `json:"translations,omitempty"`
+}
+
+// Lines are the song's lyrics line by line, the lines translations are
+// aligned with.
+func (s *Song) Lines() []string {
+	return strings.Split(songText(s), "\n")
+}
+
+func (c *CachedSong) song() *Song {
+	var fetchedAt time.Time
+	if c.FetchedAt != nil {
+		fetchedAt = *c.FetchedAt
+	}
+	return &Song{
+		Artist:           c.Artist,
+		Title:            c.Title,
+		Lyrics:           c.Lyrics,
+		SyncedLyrics:     c.SyncedLyrics,
+		HasSyncedLyrics:  c.HasSyncedLyrics,
+		GeniusID:         c.GeniusID,
+		Sections:         c.Sections,
+		Source:           c.Source,
+		Model:            c.Model,
+		FetchedAt:        fetchedAt,
+		Duration:         c.Duration,
+		Confidence:       c.Confidence,
+		IdentifiedArtist: c.IdentifiedArtist,
+		IdentifiedTitle:  c.IdentifiedTitle,
+		Verification:     c.Verification,
+	}
 }
@@ -0,0 +1,149 @@
+package lyrics
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// minMatchScore is the lowest combined artist/title similarity accepted as
+// the same song. Below it, returning nothing beats returning wrong lyrics.
+const minMatchScore = 0.6
+
+// translationMarkers identify translation and romanization pages, which
+// Genius files under accounts like "Genius English Translations".
+var translationMarkers = []string{
+	"translation", "translations", "traduccion", "traducción", "traduction",
+	"traduzione", "übersetzung", "romanized", "romanizations", "romanization",
+}
+
+// geniusAccounts are Genius' own accounts, which file translations and
+// romanizations of other artists' songs under their name.
+var geniusAccounts = map[string]bool{
+	"genius":                                         true,
+	"genius english translations":                    true,
+	"genius romanizations":                           true,
+	"genius traducciones al español":                 true,
+	"genius traductions françaises":                  true,
+	"genius brasil traduções":                        true,
+	"genius deutsche übersetzungen":                  true,
+	"genius traduzioni italiane":                     true,
+	"genius türkçe çeviri":                           true,
+	"genius polska tłumaczenia":                      true,
+	"genius russian translations (русские переводы)": true,
+	"genius japanese translations (日本語訳)":            true,
+	"genius korean translations (한국어 번역)":            true,
+}
+
+// bestGeniusHit picks the search hit that best matches the requested song,
+// along with its score.
+// Translation and romanization pages are skipped unless the request itself
+// asks for one.
+func bestGeniusHit(hits []geniusHit, artist, title string) (geniusHit, float64, bool) {
+	wantTranslation := isTranslation(artist) || isTranslation(title)
+
+	var best geniusHit
+	bestScore := 0.0
+	for _, hit := range hits {
+		if !wantTranslation && (geniusAccounts[strings.ToLower(hit.PrimaryArtist.Name)] ||
+			isTranslation(hit.PrimaryArtist.Name) || isTranslation(hit.Title) || isTranslationURL(hit.URL)) {
+			continue
+		}
+
+		artistScore := similarity(artist, hit.PrimaryArtist.Name)
+		if s := similarity(artist, hit.ArtistNames); s > artistScore {
+			artistScore = s
+		}
+		titleScore := similarity(title, hit.Title)
+		if artistScore < 0.5 || titleScore < 0.5 {
+			continue
+		}
+
+		score := (artistScore + titleScore) / 2
+		if score > bestScore {
+			best = hit
+			bestScore = score
+		}
+	}
+
+	return best, bestScore, bestScore >= minMatchScore
+}
+
+// isTranslationURL reports whether a Genius page URL is a translation page,
+// whose path ends like "-english-translation-lyrics".
+func isTranslationURL(pageURL string) bool {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return false
+	}
+	path := strings.TrimSuffix(strings.ToLower(u.Path), "-lyrics")
+	for _, marker := range translationMarkers {
+		if strings.HasSuffix(path, "-"+marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTranslation(s string) bool {
+	s = strings.ToLower(s)
+	for _, marker := range translationMarkers {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// similarity scores two names between 0 and 1. Exact matches after
+// normalization score 1, containment (e.g. "Song" vs "Song - Remastered")
+// scores 0.9, anything else is the token overlap.
+func similarity(a, b string) float64 {
+	na, nb := normalizeName(a), normalizeName(b)
+	if na == "" || nb == "" {
+		return 0
+	}
+	if na == nb {
+		return 1
+	}
+	if strings.Contains(" "+na+" ", " "+nb+" ") || strings.Contains(" "+nb+" ", " "+na+" ") {
+		return 0.9
+	}
+
+	return tokenOverlap(strings.Fields(na), strings.Fields(nb))
+}
+
+// tokenOverlap is the share of tokens two lists have in common, from 0 to
+// 1.
+func tokenOverlap(ta, tb []string) float64 {
+	if len(ta)+len(tb) == 0 {
+		return 0
+	}
+	seen := map[string]int{}
+	for _, t := range ta {
+		seen[t]++
+	}
+	common := 0
+	for _, t := range tb {
+		if seen[t] > 0 {
+			seen[t]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(ta)+len(tb))
+}
+
+var (
+	featRegex        = regexp.MustCompile(`(?i)[(\[]?\b(feat|ft|featuring)\b\.?.*$`)
+	parentheticRegex = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]`)
+	nonWordRegex     = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+func normalizeName(s string) string {
+	s = strings.ToLower(s)
+	s = featRegex.ReplaceAllString(s, "")
+	s = parentheticRegex.ReplaceAllString(s, " ")
+	s = strings.ReplaceAll(s, "&", " and ")
+	s = nonWordRegex.ReplaceAllString(s, " ")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,70 @@
+package lyrics
+
+import "testing"
+
+func geniusTestHit(id int, artist, title, url string) geniusHit {
+	hit := geniusHit{ID: id, Title: title, ArtistNames: artist, URL: url}
+	hit.PrimaryArtist.Name = artist
+	return hit
+}
+
+func TestBestGeniusHit(t *testing.T) {
+	tests := []struct {
+		name          string
+		hits          []geniusHit
+		artist, title string
+		wantID        int
+		wantOK        bool
+	}{
+		{
+			name: "skips translation accounts",
+			hits: []geniusHit{
+				geniusTestHit(1, "Genius English Translations", "Rosalía - Malamente (English Translation)", "https://genius.com/Genius-english-translations-rosalia-malamente-english-translation-lyrics"),
+				geniusTestHit(2, "Rosalía", "MALAMENTE", "https://genius.com/Rosalia-malamente-lyrics"),
+			},
+			artist: "Rosalía", title: "Malamente",
+			wantID: 2, wantOK: true,
+		},
+		{
+			name: "keeps artists whose name starts with Genius",
+			hits: []geniusHit{
+				geniusTestHit(3, "Genius Child", "Sunrise", "https://genius.com/Genius-child-sunrise-lyrics"),
+			},
+			artist: "Genius Child", title: "Sunrise",
+			wantID: 3, wantOK: true,
+		},
+		{
+			name: "skips translation pages by url",
+			hits: []geniusHit{
+				geniusTestHit(4, "Some Fan", "Song", "https://genius.com/Some-fan-song-romanized-lyrics"),
+			},
+			artist: "Some Fan", title: "Song",
+			wantOK: false,
+		},
+		{
+			name: "keeps translations when asked for",
+			hits: []geniusHit{
+				geniusTestHit(5, "Genius English Translations", "Malamente (English Translation)", "https://genius.com/Genius-english-translations-rosalia-malamente-english-translation-lyrics"),
+			},
+			artist: "Genius English Translations", title: "Malamente English Translation",
+			wantID: 5, wantOK: true,
+		},
+		{
+			name: "rejects weak matches",
+			hits: []geniusHit{
+				geniusTestHit(6, "Other Artist", "Other Song", "https://genius.com/Other-artist-other-song-lyrics"),
+			},
+			artist: "Rosalía", title: "Malamente",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hit, _, ok := bestGeniusHit(tt.hits, tt.artist, tt.title)
+			if ok != tt.wantOK || (ok && hit.ID != tt.wantID) {
+				t.Errorf("bestGeniusHit() = %d, %v; want %d, %v", hit.ID, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
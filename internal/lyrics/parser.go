@@ -22,12 +22,13 @@ func ParseLRC(lrcContent string) []Line {
 			fmt.Sscanf(matches[3], "%d", &centiseconds)
 
 			timestamp := float64(minutes*60+seconds) + float64(centiseconds)/100.0
-			text := strings.TrimSpace(matches[4])
+			text, words := parseLRCWords(strings.TrimSpace(matches[4]))
 
 			if text != "" {
 				lines = append(lines, Line{
 					Timestamp: timestamp,
 					Text:      text,
+					Words:     words,
 				})
 			}
 		}
@@ -36,6 +37,72 @@ func ParseLRC(lrcContent string) []Line {
 	return lines
 }
 
+// parseLRCWords splits an enhanced LRC line ("<00:01.20>Hello <00:01.80>world")
+// into its timed words. Lines without word tags are returned unchanged.
+func parseLRCWords(text string) (string, []Word) {
+	re := regexp.MustCompile(`<(\d+):(\d+(?:\.\d+)?)>`)
+	locs := re.FindAllStringSubmatchIndex(text, -1)
+	if len(locs) == 0 {
+		return text, nil
+	}
+
+	var words []Word
+	for i, loc := range locs {
+		end := len(text)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		wordText := text[loc[1]:end]
+		if i == 0 {
+			wordText = text[:loc[0]] + wordText
+		}
+		if wordText == "" {
+			// trailing tag marks the end of the previous word
+			if len(words) > 0 {
+				words[len(words)-1].End = parseLRCWordTime(text, loc)
+			}
+			continue
+		}
+		words = append(words, Word{
+			Timestamp: parseLRCWordTime(text, loc),
+			Text:      wordText,
+		})
+	}
+
+	var sb strings.Builder
+	for i := range words {
+		if i+1 < len(words) && words[i].End == 0 {
+			words[i].End = words[i+1].Timestamp
+		}
+		sb.WriteString(words[i].Text)
+	}
+	return strings.TrimSpace(sb.String()), words
+}
+
+func parseLRCWordTime(text string, loc []int) float64 {
+	minutes, _ := strconv.ParseFloat(text[loc[2]:loc[3]], 64)
+	seconds, _ := strconv.ParseFloat(text[loc[4]:loc[5]], 64)
+	return minutes*60 + seconds
+}
+
+// ParseSynced parses timed lyrics in any supported format, detecting TTML,
+// WebVTT and LRC from the content itself.
+func ParseSynced(content string) []Line {
+	trimmed := strings.TrimSpace(content)
+	switch {
+	case strings.HasPrefix(trimmed, "<") && strings.Contains(trimmed, "<tt"):
+		lines, err := ParseTTML(trimmed)
+		if err != nil {
+			return nil
+		}
+		return lines
+	case strings.HasPrefix(trimmed, "WEBVTT"):
+		return ParseVTT(trimmed)
+	default:
+		return ParseLRC(trimmed)
+	}
+}
+
 func ParseVTT(content string) []Line {
 	var lines []Line
 	// matches HH:MM:SS.mmm or MM:SS.mmm
@@ -91,6 +158,63 @@ func parseVTTTimestamp(s string) float64 {
 	return 0
 }
 
+// SplitSections splits plain lyrics into sections, treating bracketed lines
+// such as "[Chorus]" as section headers and blank lines as separators.
+func SplitSections(text string) []Section {
+	var sections []Section
+	var current *Section
+
+	flush := func() {
+		if current != nil && (current.Header != "" || len(current.Lines) > 0) {
+			sections = append(sections, *current)
+		}
+		current = nil
+	}
+
+	for _, raw := range strings.Split(text, "\n") {
+		line := strings.TrimSpace(raw)
+		switch {
+		case IsSectionHeader(line):
+			flush()
+			current = &Section{Header: strings.TrimSpace(line[1 : len(line)-1])}
+		case line == "":
+			if current != nil && len(current.Lines) > 0 {
+				flush()
+			}
+		default:
+			if current == nil {
+				current = &Section{}
+			}
+			current.Lines = append(current.Lines, line)
+		}
+	}
+	flush()
+
+	return sections
+}
+
+// JoinSections renders sections back to plain text, one bracketed header
+// line per section and a blank line between sections.
+func JoinSections(sections []Section) string {
+	var blocks []string
+	for _, section := range sections {
+		var lines []string
+		if section.Header != "" {
+			lines = append(lines, "["+section.Header+"]")
+		}
+		lines = append(lines, section.Lines...)
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// IsSectionHeader reports whether a line of plain lyrics is a section
+// marker like "[Verse 1: Artist]".
+func IsSectionHeader(line string) bool {
+	return len(line) > 2 && strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") &&
+		!strings.Contains(line[1:len(line)-1], "[")
+}
+
 func ExtractBetweenTags(text, tag string) string {
 	openTag := "<" + tag + ">"
 	closeTag := "</" + tag + ">"
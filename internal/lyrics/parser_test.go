@@ -0,0 +1,131 @@
+package lyrics
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseSyncedFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []Line
+	}{
+		{
+			fixture: "duet.ttml",
+			want: []Line{
+				{Timestamp: 1, End: 3.5, Text: "Hello world", Agent: "Alice", Words: []Word{
+					{Timestamp: 1, End: 1.6, Text: "Hello "},
+					{Timestamp: 1.6, End: 3.5, Text: "world"},
+				}},
+				{Timestamp: 4, End: 6, Text: "Second voice", Agent: "Bob", Words: []Word{
+					{Timestamp: 4, End: 4.5, Text: "Second "},
+					{Timestamp: 4.5, End: 6, Text: "voice"},
+				}},
+				{Timestamp: 7.25, End: 9, Text: "Plain line with a break", Agent: "Alice"},
+				{Timestamp: 12, End: 14, Text: "Lead (echo)", Agent: "Alice", Words: []Word{
+					{Timestamp: 12, End: 13, Text: "Lead "},
+					{Timestamp: 13, End: 14, Text: "(echo)"},
+				}},
+			},
+		},
+		{
+			fixture: "enhanced.lrc",
+			want: []Line{
+				{Timestamp: 1, Text: "Hello world", Words: []Word{
+					{Timestamp: 1, End: 1.5, Text: "Hello "},
+					{Timestamp: 1.5, End: 2.25, Text: "world"},
+				}},
+				{Timestamp: 3.1, Text: "Plain line"},
+			},
+		},
+		{
+			fixture: "malformed.ttml",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got := ParseSynced(readFixture(t, tt.fixture))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSynced(%s) =\n%+v\nwant\n%+v", tt.fixture, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTTMLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unclosed", readFixture(t, "malformed.ttml")},
+		{"no lines", `<tt xmlns="http://www.w3.org/ns/ttml"><body><div><p begin="1s"> </p></div></body></tt>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if lines, err := ParseTTML(tt.content); err == nil {
+				t.Errorf("ParseTTML() = %+v, want error", lines)
+			}
+		})
+	}
+}
+
+func TestParseTTMLTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"01:02.345", 62.345, true},
+		{"00:01:02.5", 62.5, true},
+		{"00:01:02:10", 62, true},
+		{"62.345s", 62.345, true},
+		{"1500ms", 1.5, true},
+		{"1m", 60, true},
+		{"1.5h", 5400, true},
+		{"12", 12, true},
+		{"", 0, false},
+		{"abc", 0, false},
+		{"00:xx.5", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseTTMLTime(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseTTMLTime(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseLRCWords(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantText  string
+		wantWords []Word
+	}{
+		{"no tags here", "no tags here", nil},
+		{"<00:01.00>a <00:02.00>b", "a b", []Word{
+			{Timestamp: 1, End: 2, Text: "a "},
+			{Timestamp: 2, Text: "b"},
+		}},
+		{"<01:00.5>end<01:01>", "end", []Word{
+			{Timestamp: 60.5, End: 61, Text: "end"},
+		}},
+	}
+	for _, tt := range tests {
+		text, words := parseLRCWords(tt.in)
+		if text != tt.wantText || !reflect.DeepEqual(words, tt.wantWords) {
+			t.Errorf("parseLRCWords(%q) = %q, %+v; want %q, %+v", tt.in, text, words, tt.wantText, tt.wantWords)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package lyrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueuedTrack is a lookup deferred until we are back online.
+type QueuedTrack struct {
+	Artist    string    `json:"artist"`
+	Title     string    `json:"title"`
+	Album     string    `json:"album,omitempty"`
+	Duration  float64   `json:"duration,omitempty"`
+	FirstSeen time.Time `json:"firstSeen"`
+}
+
+// Queue is a persistent list of tracks whose lyrics could not be looked up
+// while offline, stored as a single JSON file.
+type Queue struct {
+	path string
+
+	mu     sync.Mutex
+	tracks []QueuedTrack
+}
+
+// NewQueue opens the queue stored at path, starting empty if it does not
+// exist yet.
+func NewQueue(path string) *Queue {
+	q := &Queue{path: path}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &q.tracks)
+	}
+	return q
+}
+
+// Add queues track unless it is already queued.
+func (q *Queue) Add(track Track) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, t := range q.tracks {
+		if sameTrack(t.Artist, t.Title, track.Artist, track.Title) {
+			return nil
+		}
+	}
+	q.tracks = append(q.tracks, QueuedTrack{
+		Artist:    track.Artist,
+		Title:     track.Title,
+		Album:     track.Album,
+		Duration:  track.Duration,
+		FirstSeen: time.Now(),
+	})
+	return q.save()
+}
+
+// Remove drops a track from the queue.
+func (q *Queue) Remove(artist, title string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	kept := q.tracks[:0]
+	for _, t := range q.tracks {
+		if !sameTrack(t.Artist, t.Title, artist, title) {
+			kept = append(kept, t)
+		}
+	}
+	q.tracks = kept
+	return q.save()
+}
+
+// List returns the queued tracks, oldest first.
+func (q *Queue) List() []QueuedTrack {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]QueuedTrack(nil), q.tracks...)
+}
+
+// Len returns the number of queued tracks.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.tracks)
+}
+
+func (q *Queue) save() error {
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return fmt.Errorf("failed to create queue dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(q.tracks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue: %w", err)
+	}
+
+	if err := os.WriteFile(q.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write queue: %w", err)
+	}
+	return nil
+}
+
+func sameTrack(artistA, titleA, artistB, titleB string) bool {
+	return strings.EqualFold(artistA, artistB) && strings.EqualFold(titleA, titleB)
+}
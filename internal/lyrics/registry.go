@@ -0,0 +1,60 @@
+package lyrics
+
+import (
+	"sort"
+	"sync"
+)
+
+// Built-in provider IDs, as used in the configured lyrics chain.
+const (
+	ProviderLocalLRC = "local-lrc"
+	ProviderTags     = "tags"
+	ProviderLRCLIB   = "lrclib"
+	ProviderGenius   = "genius"
+	ProviderAI       = "ai"
+)
+
+// SourceImport marks lyrics imported from a file rather than fetched.
+const SourceImport = "import"
+
+// SourceCache marks search candidates found in the cache.
+const SourceCache = "cache"
+
+// Registry maps provider IDs to providers. It is safe for concurrent use so
+// providers can be swapped (e.g. after a settings change) while fetching.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+// NewRegistry creates an empty provider registry.
+func NewRegistry() *Registry {
+	return &Registry{providers: map[string]Provider{}}
+}
+
+// Register adds a provider, replacing any provider with the same ID.
+func (r *Registry) Register(id string, p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[id] = p
+}
+
+// Get returns the provider registered under id.
+func (r *Registry) Get(id string) (Provider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[id]
+	return p, ok
+}
+
+// IDs returns all registered provider IDs, sorted.
+func (r *Registry) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.providers))
+	for id := range r.providers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,33 @@
+package lyrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	first := &stubProvider{plain: "first"}
+	r.Register(ProviderLRCLIB, first)
+	r.Register(ProviderGenius, &stubProvider{plain: "genius"})
+	r.Register(ProviderTags, NewTagsProvider())
+
+	if got, want := r.IDs(), []string{ProviderGenius, ProviderLRCLIB, ProviderTags}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IDs() = %v, want %v", got, want)
+	}
+	if p, ok := r.Get(ProviderLRCLIB); !ok || p != first {
+		t.Errorf("Get(%q) = %v, %v", ProviderLRCLIB, p, ok)
+	}
+	if _, ok := r.Get("missing"); ok {
+		t.Error("Get(missing) found a provider")
+	}
+
+	second := &stubProvider{plain: "second"}
+	r.Register(ProviderLRCLIB, second)
+	if p, _ := r.Get(ProviderLRCLIB); p != second {
+		t.Error("Register did not replace the provider")
+	}
+	if len(r.IDs()) != 3 {
+		t.Errorf("IDs() = %v after replacing, want 3 ids", r.IDs())
+	}
+}
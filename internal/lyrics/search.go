@@ -0,0 +1,148 @@
+package lyrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lyrics-tui/internal/httpclient"
+)
+
+// maxCandidates bounds the candidates each search step contributes.
+const maxCandidates = 10
+
+// Search finds songs whose lyrics contain fragment: first among cached
+// songs, then through every searcher in the chain, in chain order.
+// Candidates already found are not repeated. In offline-only mode only the
+// cache is searched.
+func (s *Service) Search(ctx context.Context, fragment string) ([]Candidate, error) {
+	candidates := s.cache.Search(fragment)
+	if s.OfflineOnly() {
+		return candidates, nil
+	}
+
+	var errs []string
+	offline := false
+	for _, np := range s.providers() {
+		searcher, ok := np.provider.(Searcher)
+		if !ok {
+			continue
+		}
+		found, err := searcher.Search(ctx, fragment)
+		if ctx.Err() != nil {
+			return candidates, ctx.Err()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", np.id, err))
+			offline = offline || errors.Is(err, httpclient.ErrOffline)
+			continue
+		}
+		candidates = appendCandidates(candidates, found...)
+	}
+
+	if len(candidates) == 0 && len(errs) > 0 {
+		if offline {
+			return nil, fmt.Errorf("%w: %s", httpclient.ErrOffline, strings.Join(errs, "; "))
+		}
+		return nil, fmt.Errorf("search failed: %s", strings.Join(errs, "; "))
+	}
+	return candidates, nil
+}
+
+// Search finds cached songs whose lyrics contain fragment, ignoring case
+// and punctuation.
+func (c *Cache) Search(fragment string) []Candidate {
+	want := normalizeLyric(fragment)
+	if want == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil
+	}
+	var candidates []Candidate
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(c.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var cached CachedSong
+		if err := json.Unmarshal(data, &cached); err != nil {
+			continue
+		}
+
+		lines := strings.Split(cached.Lyrics, "\n")
+		if cached.HasSyncedLyrics {
+			lines = lines[:0]
+			for _, line := range cached.SyncedLyrics {
+				lines = append(lines, line.Text)
+			}
+		}
+		if !strings.Contains(normalizeLyric(strings.Join(lines, "\n")), want) {
+			continue
+		}
+		candidates = append(candidates, Candidate{
+			Artist:  cached.Artist,
+			Title:   cached.Title,
+			Snippet: matchingLine(lines, fragment),
+			Source:  SourceCache,
+		})
+		if len(candidates) == maxCandidates {
+			break
+		}
+	}
+	return candidates
+}
+
+// appendCandidates adds the candidates not already in list.
+func appendCandidates(list []Candidate, candidates ...Candidate) []Candidate {
+	for _, c := range candidates {
+		dup := false
+		for _, existing := range list {
+			if similarity(c.Artist, existing.Artist) == 1 && similarity(c.Title, existing.Title) == 1 {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// matchingLine returns the line containing fragment, or else the one
+// sharing the most words with it.
+func matchingLine(lines []string, fragment string) string {
+	want := normalizeLyric(fragment)
+	words := strings.Fields(want)
+
+	best, bestScore := "", 0
+	for _, line := range lines {
+		have := normalizeLyric(line)
+		if have == "" {
+			continue
+		}
+		if strings.Contains(have, want) {
+			return strings.TrimSpace(line)
+		}
+		score := 0
+		for _, w := range words {
+			if strings.Contains(" "+have+" ", " "+w+" ") {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = strings.TrimSpace(line), score
+		}
+	}
+	return best
+}
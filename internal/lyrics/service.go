@@ -1,64 +1,429 @@
 package lyrics
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
-// Service coordinates lyrics fetching from multiple providers with caching.
+	"lyrics-tui/internal/httpclient"
+)
+
+// Service coordinates lyrics fetching from a configured chain of providers
+// with caching.
 type Service struct {
-	syncedProvider Provider
-	lyricsProvider Provider
-	cache          *Cache
+	registry *Registry
+	cache    *Cache
+	queue    *Queue
+
+	mu          sync.RWMutex
+	chain       []string
+	offlineOnly bool
 }
 
-// NewService creates a new lyrics service.
-// syncedProvider is tried first for time-synced lyrics.
-// lyricsProvider is used as fallback for plain lyrics.
-func NewService(syncedProvider, lyricsProvider Provider, cache *Cache) *Service {
+// ErrQueued is returned by Fetch when a song could not be looked up offline
+// and was queued for when the network is back.
+var ErrQueued = errors.New("queued until back online")
+
+// NewService creates a new lyrics service walking chain, a list of provider
+// IDs from registry in priority order. Lookups that fail for lack of a
+// network are kept in queue.
+func NewService(registry *Registry, chain []string, cache *Cache, queue *Queue) *Service {
 	return &Service{
-		syncedProvider: syncedProvider,
-		lyricsProvider: lyricsProvider,
-		cache:          cache,
+		registry: registry,
+		chain:    chain,
+		cache:    cache,
+		queue:    queue,
 	}
 }
 
-// Fetch retrieves lyrics, trying cache first, then providers.
-// Priority: cache -> synced lyrics -> plain lyrics.
-func (s *Service) Fetch(artist, title string) (*Song, error) {
-	cached, err := s.cache.Load(artist, title)
+// Registry returns the providers available to the chain.
+func (s *Service) Registry() *Registry {
+	return s.registry
+}
+
+// SetChain replaces the ordered list of provider IDs to walk.
+func (s *Service) SetChain(chain []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chain = chain
+}
+
+// Chain returns the ordered list of provider IDs currently walked.
+func (s *Service) Chain() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.chain...)
+}
+
+// SetOfflineOnly restricts lookups to the cache and local providers. Songs
+// that are not found are queued instead.
+func (s *Service) SetOfflineOnly(offlineOnly bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offlineOnly = offlineOnly
+}
+
+// OfflineOnly reports whether lookups are restricted to local sources.
+func (s *Service) OfflineOnly() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.offlineOnly
+}
+
+// DefaultProviderTimeout bounds a single provider lookup. Providers that
+// need longer, such as exec and ai providers, say so with a Timeout method.
+const DefaultProviderTimeout = 15 * time.Second
+
+// DefaultConfidence is how sure providers are of a match unless they say
+// otherwise with a Confidence method or on the song itself.
+const DefaultConfidence = 0.7
+
+type progressKey struct{}
+
+// WithProgress returns a context under which streaming providers report
+// their partial lyrics to onPartial. Only complete songs are cached.
+func WithProgress(ctx context.Context, onPartial func(Partial)) context.Context {
+	return context.WithValue(ctx, progressKey{}, onPartial)
+}
+
+func progressFrom(ctx context.Context) func(Partial) {
+	onPartial, _ := ctx.Value(progressKey{}).(func(Partial))
+	return onPartial
+}
+
+// Fetch retrieves lyrics, trying cache first, then the provider chain.
+// Synced lyrics from any provider win over plain lyrics; within each kind
+// the chain order decides. The returned song records its Source.
+func (s *Service) Fetch(ctx context.Context, track Track) (*Song, error) {
+	cached, err := s.cache.Load(track.Artist, track.Title)
 	if err == nil {
-		return &Song{
-			Artist:          cached.Artist,
-			Title:           cached.Title,
-			Lyrics:          cached.Lyrics,
-			SyncedLyrics:    cached.SyncedLyrics,
-			HasSyncedLyrics: cached.HasSyncedLyrics,
-		}, nil
+		return cached.song(), nil
+	}
+
+	song, err := s.Refetch(ctx, track)
+	if err != nil && (s.OfflineOnly() || errors.Is(err, httpclient.ErrOffline)) {
+		if qerr := s.queue.Add(track); qerr != nil {
+			return nil, qerr
+		}
+		return nil, fmt.Errorf("%w: %s", ErrQueued, err)
+	}
+	return song, err
+}
+
+// QueuedCount returns the number of songs waiting for a lookup.
+func (s *Service) QueuedCount() int {
+	return s.queue.Len()
+}
+
+// ProcessQueue looks up queued songs, caching whatever is found. Fallback
+// providers are left out: an AI should not write lyrics for songs nobody is
+// listening to. It stops early when the network turns out to still be
+// unreachable and returns how many songs got lyrics. Songs no provider has
+// are dropped from the queue.
+func (s *Service) ProcessQueue(ctx context.Context) (int, error) {
+	found := 0
+	for _, qt := range s.queue.List() {
+		track := Track{Artist: qt.Artist, Title: qt.Title, Album: qt.Album, Duration: qt.Duration}
+		song, err := s.fetchChain(ctx, track, false)
+		if ctx.Err() != nil {
+			return found, ctx.Err()
+		}
+		if errors.Is(err, httpclient.ErrOffline) {
+			return found, err
+		}
+		if err == nil {
+			if err := s.saveToCache(qt.Artist, qt.Title, song, 0); err != nil {
+				return found, err
+			}
+			found++
+		}
+		if err := s.queue.Remove(qt.Artist, qt.Title); err != nil {
+			return found, err
+		}
 	}
+	return found, nil
+}
 
-	syncedLyrics, err := s.syncedProvider.FetchSynced(artist, title)
+// Refetch walks the provider chain ignoring the cache and caches the
+// result, keeping the offset of a song cached before.
+func (s *Service) Refetch(ctx context.Context, track Track) (*Song, error) {
+	song, err := s.fetchChain(ctx, track, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveToCache(track.Artist, track.Title, song, s.CachedOffset(track.Artist, track.Title)); err != nil {
+		return nil, err
+	}
+	return song, nil
+}
+
+// RefetchCached looks a cached song up again through the chain leaving out
+// fallback providers, to replace lyrics an AI wrote with found ones. The
+// cache, and its offset, is only updated when a provider has the song.
+func (s *Service) RefetchCached(ctx context.Context, artist, title string) (*Song, error) {
+	cached, err := s.cache.Load(artist, title)
+	if err != nil {
+		return nil, err
+	}
+
+	song, err := s.fetchChain(ctx, Track{Artist: artist, Title: title, Duration: cached.Duration}, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveToCache(artist, title, song, cached.Offset); err != nil {
+		return nil, err
+	}
+	return song, nil
+}
+
+// fetchChain races the chain's providers. Providers marking themselves as
+// fallbacks (the ai provider) only run when every other provider failed,
+// and not at all without withFallback. In offline-only mode only providers
+// marking themselves as local run.
+func (s *Service) fetchChain(ctx context.Context, track Track, withFallback bool) (*Song, error) {
+	providers := s.providers()
+	if s.OfflineOnly() {
+		var local []namedProvider
+		for _, np := range providers {
+			if l, ok := np.provider.(interface{ Local() bool }); ok && l.Local() {
+				local = append(local, np)
+			}
+		}
+		providers = local
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no lyrics providers enabled")
+	}
+
+	var primary, fallback []namedProvider
+	for _, np := range providers {
+		if f, ok := np.provider.(interface{ Fallback() bool }); ok && f.Fallback() {
+			fallback = append(fallback, np)
+		} else {
+			primary = append(primary, np)
+		}
+	}
+
+	var errs []error
+	if !withFallback {
+		fallback = nil
+	}
+	for _, group := range [][]namedProvider{primary, fallback} {
+		if len(group) == 0 {
+			continue
+		}
+		song, groupErrs := race(ctx, group, track)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if song != nil {
+			return song, nil
+		}
+		errs = append(errs, groupErrs...)
+	}
+
+	var msgs []string
+	offline := false
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+		offline = offline || errors.Is(err, httpclient.ErrOffline)
+	}
+	if offline {
+		return nil, fmt.Errorf("%w: %s", httpclient.ErrOffline, strings.Join(msgs, "; "))
+	}
+	return nil, fmt.Errorf("all providers failed: %s", strings.Join(msgs, "; "))
+}
+
+// race runs providers concurrently and returns the best song: synced lyrics
+// beat plain ones and within each kind the earlier provider wins. A synced
+// result is returned as soon as every provider ahead of it has finished,
+// and the providers still running are cancelled.
+func race(ctx context.Context, providers []namedProvider, track Track) (*Song, []error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		index int
+		song  *Song
+		err   error
+	}
+	results := make(chan result, len(providers))
+	for i, np := range providers {
+		go func(i int, np namedProvider) {
+			song, err := fetchOne(ctx, np, track)
+			results <- result{index: i, song: song, err: err}
+		}(i, np)
+	}
+
+	songs := make([]*Song, len(providers))
+	errs := make([]error, len(providers))
+	done := make([]bool, len(providers))
+	for range providers {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case r := <-results:
+			done[r.index] = true
+			songs[r.index], errs[r.index] = r.song, r.err
+		}
+
+		for i := range providers {
+			if !done[i] {
+				break
+			}
+			if songs[i] != nil && songs[i].HasSyncedLyrics {
+				return songs[i], nil
+			}
+		}
+	}
+
+	for _, song := range songs {
+		if song != nil {
+			return song, nil
+		}
+	}
+
+	var failures []error
+	for i, np := range providers {
+		if errs[i] != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", np.id, errs[i]))
+		}
+	}
+	return nil, failures
+}
+
+// fetchOne asks a single provider for a song and records where it came
+// from.
+func fetchOne(ctx context.Context, np namedProvider, track Track) (*Song, error) {
+	song, err := fetchSong(ctx, np, track)
+	if err != nil {
+		return nil, err
+	}
+	song.FetchedAt = time.Now()
+	song.Duration = track.Duration
+	if song.Confidence == 0 {
+		song.Confidence = DefaultConfidence
+		if c, ok := np.provider.(interface{ Confidence() float64 }); ok {
+			song.Confidence = c.Confidence()
+		}
+	}
+	if m, ok := np.provider.(interface{ Model() string }); ok && song.Model == "" {
+		song.Model = m.Model()
+	}
+	return song, nil
+}
+
+// fetchSong asks a single provider for a song within its deadline,
+// preferring synced lyrics.
+func fetchSong(ctx context.Context, np namedProvider, track Track) (*Song, error) {
+	timeout := DefaultProviderTimeout
+	if t, ok := np.provider.(interface{ Timeout() time.Duration }); ok {
+		timeout = t.Timeout()
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if fetcher, ok := np.provider.(SongFetcher); ok {
+		song, err := fetcher.FetchSong(ctx, track)
+		if err != nil {
+			return nil, err
+		}
+		if song.Source == "" {
+			song.Source = np.id
+		}
+		return song, nil
+	}
+
+	syncedLyrics, err := np.provider.FetchSynced(ctx, track)
 	if err == nil && len(syncedLyrics) > 0 {
-		song := &Song{
-			Artist:          artist,
-			Title:           title,
+		return &Song{
+			Artist:          track.Artist,
+			Title:           track.Title,
 			SyncedLyrics:    syncedLyrics,
 			HasSyncedLyrics: true,
+			Source:          np.id,
+		}, nil
+	}
+	if err != nil && (errors.Is(err, httpclient.ErrOffline) || ctx.Err() != nil) {
+		return nil, err
+	}
+
+	var plainLyrics string
+	streamer, ok := np.provider.(StreamingProvider)
+	if onPartial := progressFrom(ctx); ok && onPartial != nil {
+		plainLyrics, err = streamer.StreamLyrics(ctx, track, func(p Partial) {
+			p.Source = np.id
+			onPartial(p)
+		})
+	} else {
+		plainLyrics, err = np.provider.FetchLyrics(ctx, track)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if plainLyrics == "" {
+		return nil, fmt.Errorf("no lyrics found")
+	}
+	return &Song{
+		Artist: track.Artist,
+		Title:  track.Title,
+		Lyrics: plainLyrics,
+		Source: np.id,
+	}, nil
+}
+
+type namedProvider struct {
+	id       string
+	provider Provider
+}
+
+// providers resolves the chain against the registry, skipping IDs that have
+// no registered provider.
+func (s *Service) providers() []namedProvider {
+	var providers []namedProvider
+	for _, id := range s.Chain() {
+		if p, ok := s.registry.Get(id); ok {
+			providers = append(providers, namedProvider{id: id, provider: p})
 		}
-		s.saveToCache(artist, title, song, 0)
-		return song, nil
+	}
+	return providers
+}
+
+// FetchAnnotations retrieves annotations for a song found on Genius and maps
+// them onto its plain lyrics lines.
+func (s *Service) FetchAnnotations(ctx context.Context, geniusID int, plainLyrics string) ([]Annotation, error) {
+	p, _ := s.registry.Get(ProviderGenius)
+	provider, ok := p.(interface {
+		FetchAnnotations(ctx context.Context, songID int) ([]Annotation, error)
+	})
+	if !ok || geniusID == 0 {
+		return nil, fmt.Errorf("annotations are not available for this song")
 	}
 
-	plainLyrics, err := s.lyricsProvider.FetchLyrics(artist, title)
+	annotations, err := provider.FetchAnnotations(ctx, geniusID)
 	if err != nil {
-		return nil, fmt.Errorf("all providers failed: %w", err)
+		return nil, err
 	}
+	return MapAnnotations(plainLyrics, annotations), nil
+}
 
-	song := &Song{
-		Artist:          artist,
-		Title:           title,
-		Lyrics:          plainLyrics,
-		HasSyncedLyrics: false,
+// Diagnostics returns debug output from providers that keep some, such as
+// the stderr of exec providers, keyed by provider ID.
+func (s *Service) Diagnostics() map[string]string {
+	diagnostics := map[string]string{}
+	for _, id := range s.registry.IDs() {
+		p, _ := s.registry.Get(id)
+		if d, ok := p.(interface{ Diagnostics() string }); ok {
+			if out := d.Diagnostics(); out != "" {
+				diagnostics[id] = out
+			}
+		}
 	}
-	s.saveToCache(artist, title, song, 0)
-	return song, nil
+	return diagnostics
 }
 
 // LoadFromCache retrieves a song from cache, including offset.
@@ -71,11 +436,76 @@ func (s *Service) SaveToCache(artist, title string, song *Song, offset float64)
 	return s.saveToCache(artist, title, song, offset)
 }
 
+// Import stores timed lyrics in any supported format (LRC, TTML, WebVTT)
+// in the cache so they are used the next time the song is looked up.
+func (s *Service) Import(artist, title, content string) (*Song, error) {
+	lines := ParseSynced(content)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no timed lyrics found")
+	}
+
+	song := &Song{
+		Artist:          artist,
+		Title:           title,
+		SyncedLyrics:    lines,
+		HasSyncedLyrics: true,
+		Source:          SourceImport,
+		FetchedAt:       time.Now(),
+		Confidence:      1,
+	}
+	if err := s.saveToCache(artist, title, song, 0); err != nil {
+		return nil, err
+	}
+	return song, nil
+}
+
+// ImportFile reads a lyrics file from disk and imports it into the cache.
+func (s *Service) ImportFile(path, artist, title string) (*Song, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lyrics file: %w", err)
+	}
+	return s.Import(artist, title, string(data))
+}
+
+// CachedOffset returns the timing offset saved for a song, 0 when it isn't
+// cached.
+func (s *Service) CachedOffset(artist, title string) float64 {
+	cached, err := s.cache.Load(artist, title)
+	if err != nil {
+		return 0
+	}
+	return cached.Offset
+}
+
 // UpdateOffset updates the timing offset for cached lyrics.
 func (s *Service) UpdateOffset(artist, title string, offset float64) error {
 	return s.cache.UpdateOffset(artist, title, offset)
 }
 
+// Translation returns the cached translation of a song into language, nil
+// when there is none.
+func (s *Service) Translation(artist, title, language string) []string {
+	cached, err := s.cache.Load(artist, title)
+	if err != nil {
+		return nil
+	}
+	return cached.Translations[language]
+}
+
+// SaveTranslation stores a translation of cached lyrics, one entry per line.
+// Translations that don't line up with the cached lyrics are refused.
+func (s *Service) SaveTranslation(artist, title, language string, lines []string) error {
+	cached, err := s.cache.Load(artist, title)
+	if err != nil {
+		return err
+	}
+	if n := len(cached.song().Lines()); n != len(lines) {
+		return fmt.Errorf("translation has %d lines, lyrics of %s - %s have %d", len(lines), artist, title, n)
+	}
+	return s.cache.UpdateTranslation(artist, title, language, lines)
+}
+
 func (s *Service) ListAllCached() []CachedSongEntry {
 	return s.cache.ListAll()
 }
@@ -90,12 +520,29 @@ func (s *Service) CachedSongCount() int {
 
 func (s *Service) saveToCache(artist, title string, song *Song, offset float64) error {
 	cached := &CachedSong{
-		Artist:          artist,
-		Title:           title,
-		Lyrics:          song.Lyrics,
-		SyncedLyrics:    song.SyncedLyrics,
-		HasSyncedLyrics: song.HasSyncedLyrics,
-		Offset:          offset,
+		Artist:           artist,
+		Title:            title,
+		Lyrics:           song.Lyrics,
+		SyncedLyrics:     song.SyncedLyrics,
+		HasSyncedLyrics:  song.HasSyncedLyrics,
+		Offset:           offset,
+		GeniusID:         song.GeniusID,
+		Sections:         song.Sections,
+		Source:           song.Source,
+		Model:            song.Model,
+		Duration:         song.Duration,
+		Confidence:       song.Confidence,
+		IdentifiedArtist: song.IdentifiedArtist,
+		IdentifiedTitle:  song.IdentifiedTitle,
+		Verification:     song.Verification,
+	}
+	if !song.FetchedAt.IsZero() {
+		fetchedAt := song.FetchedAt
+		cached.FetchedAt = &fetchedAt
+	}
+	// translations still fit when the lyrics are saved again unchanged
+	if old, err := s.cache.Load(artist, title); err == nil && songText(old.song()) == songText(song) {
+		cached.Translations = old.Translations
 	}
 	return s.cache.Save(cached)
 }
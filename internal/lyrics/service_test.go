@@ -0,0 +1,291 @@
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubProvider answers with fixed lyrics after an optional delay.
+type stubProvider struct {
+	plain    string
+	synced   []Line
+	delay    time.Duration
+	fallback bool
+	local    bool
+	calls    int32
+}
+
+func (p *stubProvider) FetchLyrics(ctx context.Context, track Track) (string, error) {
+	atomic.AddInt32(&p.calls, 1)
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if p.plain == "" {
+		return "", errors.New("not found")
+	}
+	return p.plain, nil
+}
+
+func (p *stubProvider) FetchSynced(ctx context.Context, track Track) ([]Line, error) {
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if len(p.synced) == 0 {
+		return nil, errors.New("no synced lyrics")
+	}
+	return p.synced, nil
+}
+
+func (p *stubProvider) Fallback() bool { return p.fallback }
+func (p *stubProvider) Local() bool    { return p.local }
+
+func (p *stubProvider) called() bool {
+	return atomic.LoadInt32(&p.calls) > 0
+}
+
+func newTestService(t *testing.T, chain []string, providers map[string]Provider) *Service {
+	t.Helper()
+	registry := NewRegistry()
+	for id, p := range providers {
+		registry.Register(id, p)
+	}
+	dir := t.TempDir()
+	return NewService(registry, chain, NewCache(filepath.Join(dir, "cache")), NewQueue(filepath.Join(dir, "queue.json")))
+}
+
+func TestChainOrder(t *testing.T) {
+	synced := []Line{{Timestamp: 1, Text: "synced"}}
+	tests := []struct {
+		name       string
+		chain      []string
+		providers  map[string]Provider
+		wantSource string
+		wantSynced bool
+	}{
+		{
+			name:  "synced beats an earlier plain result",
+			chain: []string{"a", "b"},
+			providers: map[string]Provider{
+				"a": &stubProvider{plain: "plain"},
+				"b": &stubProvider{synced: synced, delay: 20 * time.Millisecond},
+			},
+			wantSource: "b", wantSynced: true,
+		},
+		{
+			name:  "earlier provider wins between plain results",
+			chain: []string{"a", "b"},
+			providers: map[string]Provider{
+				"a": &stubProvider{plain: "slow", delay: 30 * time.Millisecond},
+				"b": &stubProvider{plain: "fast"},
+			},
+			wantSource: "a",
+		},
+		{
+			name:  "earlier provider wins between synced results",
+			chain: []string{"b", "a"},
+			providers: map[string]Provider{
+				"a": &stubProvider{synced: synced},
+				"b": &stubProvider{synced: synced, delay: 30 * time.Millisecond},
+			},
+			wantSource: "b", wantSynced: true,
+		},
+		{
+			name:  "providers missing from the registry are skipped",
+			chain: []string{"missing", "a"},
+			providers: map[string]Provider{
+				"a": &stubProvider{plain: "plain"},
+			},
+			wantSource: "a",
+		},
+		{
+			name:  "fallback runs when every other provider fails",
+			chain: []string{"ai", "a"},
+			providers: map[string]Provider{
+				"ai": &stubProvider{plain: "written", fallback: true},
+				"a":  &stubProvider{},
+			},
+			wantSource: "ai",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, tt.chain, tt.providers)
+			song, err := s.Refetch(context.Background(), Track{Artist: "A", Title: "T"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if song.Source != tt.wantSource || song.HasSyncedLyrics != tt.wantSynced {
+				t.Errorf("Refetch() from %q (synced %v), want %q (synced %v)", song.Source, song.HasSyncedLyrics, tt.wantSource, tt.wantSynced)
+			}
+			cached, err := s.LoadFromCache("A", "T")
+			if err != nil || cached.Source != tt.wantSource {
+				t.Errorf("cached %+v, %v; want source %q", cached, err, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestChainSkipsFallbackWhenFound(t *testing.T) {
+	ai := &stubProvider{plain: "written", fallback: true}
+	s := newTestService(t, []string{"ai", "a"}, map[string]Provider{
+		"ai": ai,
+		"a":  &stubProvider{plain: "found"},
+	})
+	song, err := s.Refetch(context.Background(), Track{Artist: "A", Title: "T"})
+	if err != nil || song.Source != "a" {
+		t.Fatalf("Refetch() = %+v, %v; want lyrics from a", song, err)
+	}
+	if ai.called() {
+		t.Error("fallback provider ran although another provider had the song")
+	}
+}
+
+func TestChainOfflineOnly(t *testing.T) {
+	remote := &stubProvider{plain: "remote"}
+	s := newTestService(t, []string{"remote", "local"}, map[string]Provider{
+		"remote": remote,
+		"local":  &stubProvider{plain: "local", local: true},
+	})
+	s.SetOfflineOnly(true)
+
+	song, err := s.Fetch(context.Background(), Track{Artist: "A", Title: "T"})
+	if err != nil || song.Source != "local" {
+		t.Fatalf("Fetch() = %+v, %v; want lyrics from local", song, err)
+	}
+	if remote.called() {
+		t.Error("remote provider ran in offline-only mode")
+	}
+}
+
+func TestChainFailure(t *testing.T) {
+	s := newTestService(t, []string{"a", "b"}, map[string]Provider{
+		"a": &stubProvider{},
+		"b": &stubProvider{},
+	})
+	if song, err := s.Refetch(context.Background(), Track{Artist: "A", Title: "T"}); err == nil {
+		t.Fatalf("Refetch() = %+v, want error", song)
+	}
+	if _, err := s.LoadFromCache("A", "T"); err == nil {
+		t.Error("failed lookup was cached")
+	}
+
+	empty := newTestService(t, nil, nil)
+	if _, err := empty.Refetch(context.Background(), Track{Artist: "A", Title: "T"}); err == nil {
+		t.Error("Refetch() with an empty chain succeeded")
+	}
+}
+
+func TestProcessQueueSkipsFallback(t *testing.T) {
+	ai := &stubProvider{plain: "written", fallback: true}
+	s := newTestService(t, []string{"a", "ai"}, map[string]Provider{
+		"a":  &stubProvider{},
+		"ai": ai,
+	})
+	if err := s.queue.Add(Track{Artist: "A", Title: "T"}); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := s.ProcessQueue(context.Background())
+	if err != nil || found != 0 {
+		t.Fatalf("ProcessQueue() = %d, %v; want 0, nil", found, err)
+	}
+	if ai.called() {
+		t.Error("fallback provider ran for a queued song")
+	}
+	if s.QueuedCount() != 0 {
+		t.Error("song no provider has was kept in the queue")
+	}
+
+	s = newTestService(t, []string{"a", "ai"}, map[string]Provider{
+		"a":  &stubProvider{plain: "found"},
+		"ai": ai,
+	})
+	s.queue.Add(Track{Artist: "A", Title: "T"})
+	if found, err := s.ProcessQueue(context.Background()); err != nil || found != 1 {
+		t.Fatalf("ProcessQueue() = %d, %v; want 1, nil", found, err)
+	}
+	if cached, err := s.LoadFromCache("A", "T"); err != nil || cached.Lyrics != "found" {
+		t.Errorf("LoadFromCache() = %+v, %v; want the found lyrics", cached, err)
+	}
+}
+
+func TestCachedFetchedAt(t *testing.T) {
+	s := newTestService(t, nil, nil)
+	fetchedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		song *Song
+	}{
+		{"recorded", &Song{Lyrics: "found", FetchedAt: fetchedAt}},
+		{"unknown", &Song{Lyrics: "older"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.saveToCache("Artist", tt.name, tt.song, 0); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(s.cache.cachePath("Artist", tt.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored := strings.Contains(string(data), "fetchedAt"); stored != !tt.song.FetchedAt.IsZero() {
+				t.Errorf("cache entry %s", data)
+			}
+
+			cached, err := s.LoadFromCache("Artist", tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cached.song().FetchedAt; !got.Equal(tt.song.FetchedAt) {
+				t.Errorf("FetchedAt = %v, want %v", got, tt.song.FetchedAt)
+			}
+		})
+	}
+}
+
+func TestRefetchKeepsOffset(t *testing.T) {
+	s := newTestService(t, []string{"a"}, map[string]Provider{"a": &stubProvider{plain: "new lyrics"}})
+	if err := s.saveToCache("A", "T", &Song{Lyrics: "old lyrics"}, 1.5); err != nil {
+		t.Fatal(err)
+	}
+
+	song, err := s.Refetch(context.Background(), Track{Artist: "A", Title: "T"})
+	if err != nil || song.Lyrics != "new lyrics" {
+		t.Fatalf("Refetch() = %+v, %v", song, err)
+	}
+	cached, err := s.LoadFromCache("A", "T")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Lyrics != "new lyrics" || cached.Offset != 1.5 {
+		t.Errorf("cached %q with offset %v, want the new lyrics with the old offset", cached.Lyrics, cached.Offset)
+	}
+}
+
+func TestRefetchCacheError(t *testing.T) {
+	dir := t.TempDir()
+	// the cache directory is a file, so nothing can be saved
+	blocked := filepath.Join(dir, "cache")
+	if err := os.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	registry := NewRegistry()
+	registry.Register("a", &stubProvider{plain: "lyrics"})
+	s := NewService(registry, []string{"a"}, NewCache(blocked), NewQueue(filepath.Join(dir, "queue.json")))
+
+	if song, err := s.Refetch(context.Background(), Track{Artist: "A", Title: "T"}); err == nil {
+		t.Errorf("Refetch() = %+v, want the cache error", song)
+	}
+}
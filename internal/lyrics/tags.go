@@ -0,0 +1,325 @@
+package lyrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// TagsProvider reads lyrics embedded in the tags of the audio file being
+// played: ID3v2 USLT frames (MP3), Vorbis comments (FLAC) and the ©lyr atom
+// (MP4/M4A). Lyrics in LRC format are returned as synced lyrics.
+type TagsProvider struct{}
+
+// NewTagsProvider creates a provider reading lyrics from audio file tags.
+func NewTagsProvider() *TagsProvider {
+	return &TagsProvider{}
+}
+
+// Local marks the provider as usable in offline-only mode.
+func (p *TagsProvider) Local() bool {
+	return true
+}
+
+// Confidence is full: the lyrics are part of the file being played.
+func (p *TagsProvider) Confidence() float64 {
+	return 1
+}
+
+// FetchLyrics retrieves the embedded lyrics as plain text.
+func (p *TagsProvider) FetchLyrics(ctx context.Context, track Track) (string, error) {
+	return readEmbeddedLyrics(track.FilePath)
+}
+
+// FetchSynced retrieves embedded lyrics stored in LRC format.
+func (p *TagsProvider) FetchSynced(ctx context.Context, track Track) ([]Line, error) {
+	text, err := readEmbeddedLyrics(track.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	lines := ParseSynced(text)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("embedded lyrics are not synced")
+	}
+	return lines, nil
+}
+
+// readEmbeddedLyrics returns the lyrics tag of an audio file, detecting the
+// container from its first bytes.
+func readEmbeddedLyrics(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("track is not played from a local file")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open audio file: %w", err)
+	}
+	defer f.Close()
+
+	magic := make([]byte, 8)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return "", fmt.Errorf("failed to read audio file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	var text string
+	switch {
+	case bytes.HasPrefix(magic, []byte("ID3")):
+		text, err = readID3Lyrics(f)
+	case bytes.HasPrefix(magic, []byte("fLaC")):
+		text, err = readFLACLyrics(f)
+	case string(magic[4:8]) == "ftyp":
+		text, err = readMP4Lyrics(f)
+	default:
+		return "", fmt.Errorf("unsupported audio file %s", filepath.Base(path))
+	}
+	if err != nil {
+		return "", err
+	}
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return "", fmt.Errorf("no embedded lyrics in %s", filepath.Base(path))
+	}
+	return text, nil
+}
+
+// readID3Lyrics returns the text of the first USLT frame of an ID3v2.3 or
+// v2.4 tag.
+func readID3Lyrics(r io.Reader) (string, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", fmt.Errorf("failed to read id3 header: %w", err)
+	}
+	version, flags := header[3], header[5]
+	if version != 3 && version != 4 {
+		return "", fmt.Errorf("unsupported id3v2.%d tag", version)
+	}
+	tag := make([]byte, syncsafe(header[6:10]))
+	if _, err := io.ReadFull(r, tag); err != nil {
+		return "", fmt.Errorf("failed to read id3 tag: %w", err)
+	}
+	if flags&0x80 != 0 {
+		tag = bytes.ReplaceAll(tag, []byte{0xff, 0x00}, []byte{0xff})
+	}
+	if flags&0x40 != 0 && len(tag) >= 4 {
+		size := int(binary.BigEndian.Uint32(tag[:4])) + 4
+		if version == 4 {
+			size = syncsafe(tag[:4])
+		}
+		if size > len(tag) {
+			return "", fmt.Errorf("invalid id3 extended header")
+		}
+		tag = tag[size:]
+	}
+
+	for len(tag) >= 10 && tag[0] != 0 {
+		id := string(tag[:4])
+		size := int(binary.BigEndian.Uint32(tag[4:8]))
+		if version == 4 {
+			size = syncsafe(tag[4:8])
+		}
+		if size > len(tag)-10 {
+			return "", fmt.Errorf("invalid id3 frame %s", id)
+		}
+		frame := tag[10 : 10+size]
+		tag = tag[10+size:]
+		if id == "USLT" && len(frame) > 4 {
+			return decodeUSLT(frame[0], frame[4:]), nil
+		}
+	}
+	return "", nil
+}
+
+// decodeUSLT skips the content descriptor of a USLT frame body and decodes
+// the lyrics after it.
+func decodeUSLT(encoding byte, body []byte) string {
+	terminator := []byte{0}
+	if encoding == 1 || encoding == 2 {
+		terminator = []byte{0, 0}
+	}
+	for i := 0; i+len(terminator) <= len(body); i += len(terminator) {
+		if bytes.Equal(body[i:i+len(terminator)], terminator) {
+			return decodeID3Text(encoding, body[i+len(terminator):])
+		}
+	}
+	return ""
+}
+
+// decodeID3Text decodes ID3 text in one of its four encodings.
+func decodeID3Text(encoding byte, b []byte) string {
+	switch encoding {
+	case 0:
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return strings.TrimRight(string(runes), "\x00")
+	case 1, 2:
+		order := binary.ByteOrder(binary.BigEndian)
+		if encoding == 1 && len(b) >= 2 {
+			if b[0] == 0xff && b[1] == 0xfe {
+				order = binary.LittleEndian
+			}
+			if (b[0] == 0xff && b[1] == 0xfe) || (b[0] == 0xfe && b[1] == 0xff) {
+				b = b[2:]
+			}
+		}
+		units := make([]uint16, len(b)/2)
+		for i := range units {
+			units[i] = order.Uint16(b[2*i:])
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+	default:
+		return strings.TrimRight(string(b), "\x00")
+	}
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0])<<21 | int(b[1])<<14 | int(b[2])<<7 | int(b[3])
+}
+
+// readFLACLyrics returns the LYRICS or UNSYNCEDLYRICS Vorbis comment of a
+// FLAC file.
+func readFLACLyrics(r io.Reader) (string, error) {
+	if _, err := io.ReadFull(r, make([]byte, 4)); err != nil {
+		return "", err
+	}
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return "", fmt.Errorf("failed to read flac metadata: %w", err)
+		}
+		last, blockType := header[0]&0x80 != 0, header[0]&0x7f
+		size := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+		block := make([]byte, size)
+		if _, err := io.ReadFull(r, block); err != nil {
+			return "", fmt.Errorf("failed to read flac metadata: %w", err)
+		}
+		if blockType == 4 {
+			return vorbisLyrics(block)
+		}
+		if last {
+			return "", nil
+		}
+	}
+}
+
+// vorbisLyrics finds the lyrics in a Vorbis comment block.
+func vorbisLyrics(block []byte) (string, error) {
+	next := func() ([]byte, error) {
+		if len(block) < 4 {
+			return nil, fmt.Errorf("invalid vorbis comment")
+		}
+		n := int(binary.LittleEndian.Uint32(block))
+		if n > len(block)-4 {
+			return nil, fmt.Errorf("invalid vorbis comment")
+		}
+		field := block[4 : 4+n]
+		block = block[4+n:]
+		return field, nil
+	}
+
+	if _, err := next(); err != nil { // vendor
+		return "", err
+	}
+	if len(block) < 4 {
+		return "", fmt.Errorf("invalid vorbis comment")
+	}
+	count := int(binary.LittleEndian.Uint32(block))
+	block = block[4:]
+	for i := 0; i < count; i++ {
+		field, err := next()
+		if err != nil {
+			return "", err
+		}
+		parts := strings.SplitN(string(field), "=", 2)
+		if len(parts) == 2 && (strings.EqualFold(parts[0], "LYRICS") || strings.EqualFold(parts[0], "UNSYNCEDLYRICS")) {
+			return parts[1], nil
+		}
+	}
+	return "", nil
+}
+
+// readMP4Lyrics returns the ©lyr atom of an MP4 file, found under
+// moov/udta/meta/ilst.
+func readMP4Lyrics(r io.ReadSeeker) (string, error) {
+	moov, err := findTopLevelBox(r, "moov")
+	if err != nil {
+		return "", err
+	}
+	box := moov
+	for _, name := range []string{"udta", "meta", "ilst", "\xa9lyr", "data"} {
+		box = findBox(box, name)
+		if box == nil {
+			return "", nil
+		}
+		if name == "meta" && len(box) >= 4 {
+			box = box[4:] // version and flags
+		}
+	}
+	if len(box) < 8 {
+		return "", nil
+	}
+	return string(box[8:]), nil // type indicator and locale
+}
+
+// findTopLevelBox reads the body of the first box named name, skipping the
+// media data before it.
+func findTopLevelBox(r io.ReadSeeker, name string) ([]byte, error) {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, fmt.Errorf("no %s box in mp4 file", name)
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+		if size == 1 {
+			large := make([]byte, 8)
+			if _, err := io.ReadFull(r, large); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(large))
+			headerSize = 16
+		}
+		if size != 0 && size < headerSize {
+			return nil, fmt.Errorf("invalid mp4 box")
+		}
+		if string(header[4:8]) == name {
+			if size == 0 {
+				return io.ReadAll(r)
+			}
+			body := make([]byte, size-headerSize)
+			_, err := io.ReadFull(r, body)
+			return body, err
+		}
+		if size == 0 {
+			return nil, fmt.Errorf("no %s box in mp4 file", name)
+		}
+		if _, err := r.Seek(size-headerSize, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// findBox returns the body of the box named name among the boxes in b.
+func findBox(b []byte, name string) []byte {
+	for len(b) >= 8 {
+		size := int(binary.BigEndian.Uint32(b))
+		if size < 8 || size > len(b) {
+			return nil
+		}
+		if string(b[4:8]) == name {
+			return b[8:size]
+		}
+		b = b[size:]
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package lyrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
+
+// id3File builds an MP3 file starting with an ID3v2 tag holding one USLT
+// frame.
+func id3File(version byte, encoding byte, text []byte) []byte {
+	body := append([]byte{encoding, 'e', 'n', 'g'}, 0) // empty descriptor
+	if encoding == 1 || encoding == 2 {
+		body = append(body, 0)
+	}
+	body = append(body, text...)
+
+	var frame bytes.Buffer
+	frame.WriteString("TIT2")
+	frame.Write([]byte{0, 0, 0, 3, 0, 0})
+	frame.Write([]byte{3, 'H', 'i'})
+	frame.WriteString("USLT")
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(body)))
+	if version == 4 {
+		size = syncsafeBytes(len(body))
+	}
+	frame.Write(size)
+	frame.Write([]byte{0, 0})
+	frame.Write(body)
+	frame.Write(make([]byte, 16)) // padding
+
+	var file bytes.Buffer
+	file.WriteString("ID3")
+	file.Write([]byte{version, 0, 0})
+	file.Write(syncsafeBytes(frame.Len()))
+	file.Write(frame.Bytes())
+	file.Write([]byte{0xff, 0xfb, 0x90, 0x00}) // audio frame
+	return file.Bytes()
+}
+
+func utf16WithBOM(s string) []byte {
+	b := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return b
+}
+
+func flacFile(comments ...string) []byte {
+	var block bytes.Buffer
+	le := func(n int) {
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, uint32(n))
+		block.Write(b)
+	}
+	le(len("test"))
+	block.WriteString("test")
+	le(len(comments))
+	for _, c := range comments {
+		le(len(c))
+		block.WriteString(c)
+	}
+
+	var file bytes.Buffer
+	file.WriteString("fLaC")
+	file.Write([]byte{0, 0, 0, 34}) // STREAMINFO
+	file.Write(make([]byte, 34))
+	n := block.Len()
+	file.Write([]byte{0x80 | 4, byte(n >> 16), byte(n >> 8), byte(n)})
+	file.Write(block.Bytes())
+	return file.Bytes()
+}
+
+func mp4Box(name string, children ...[]byte) []byte {
+	body := bytes.Join(children, nil)
+	b := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(b, uint32(8+len(body)))
+	copy(b[4:], name)
+	return append(b, body...)
+}
+
+func mp4File(lyrics string) []byte {
+	data := mp4Box("data", []byte{0, 0, 0, 1, 0, 0, 0, 0}, []byte(lyrics))
+	meta := mp4Box("meta", []byte{0, 0, 0, 0}, mp4Box("hdlr", make([]byte, 25)), mp4Box("ilst", mp4Box("\xa9lyr", data)))
+	return bytes.Join([][]byte{
+		mp4Box("ftyp", []byte("M4A "), make([]byte, 4)),
+		mp4Box("mdat", make([]byte, 64)),
+		mp4Box("moov", mp4Box("mvhd", make([]byte, 100)), mp4Box("udta", meta)),
+	}, nil)
+}
+
+func TestTagsProvider(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       []byte
+		wantPlain  string
+		wantSynced int
+		wantErr    bool
+	}{
+		{name: "id3v2.3 utf-16", file: id3File(3, 1, utf16WithBOM("Héllo\r\nwörld")), wantPlain: "Héllo\nwörld"},
+		{name: "id3v2.4 utf-8 lrc", file: id3File(4, 3, []byte("[00:01.00]One\n[00:02.50]Two")), wantPlain: "[00:01.00]One\n[00:02.50]Two", wantSynced: 2},
+		{name: "id3v2.3 latin-1", file: id3File(3, 0, []byte{'c', 0xe9, 'u'}), wantPlain: "céu"},
+		{name: "flac", file: flacFile("TITLE=Song", "lyrics=First\nSecond"), wantPlain: "First\nSecond"},
+		{name: "flac unsynced key", file: flacFile("UNSYNCEDLYRICS=Only line"), wantPlain: "Only line"},
+		{name: "flac without lyrics", file: flacFile("TITLE=Song"), wantErr: true},
+		{name: "mp4", file: mp4File("Line one\nLine two"), wantPlain: "Line one\nLine two"},
+		{name: "mp4 without moov", file: mp4Box("ftyp", []byte("M4A "), make([]byte, 4)), wantErr: true},
+		{name: "unsupported", file: []byte("OggS\x00\x02\x00\x00\x00\x00"), wantErr: true},
+	}
+
+	p := NewTagsProvider()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "track")
+			if err := os.WriteFile(path, tt.file, 0644); err != nil {
+				t.Fatal(err)
+			}
+			track := Track{Artist: "A", Title: "T", FilePath: path}
+
+			plain, err := p.FetchLyrics(context.Background(), track)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("FetchLyrics() = %q, want error", plain)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plain != tt.wantPlain {
+				t.Errorf("FetchLyrics() = %q, want %q", plain, tt.wantPlain)
+			}
+
+			synced, err := p.FetchSynced(context.Background(), track)
+			if len(synced) != tt.wantSynced || (tt.wantSynced == 0) != (err != nil) {
+				t.Errorf("FetchSynced() = %d lines, %v; want %d lines", len(synced), err, tt.wantSynced)
+			}
+		})
+	}
+}
+
+func TestTagsProviderNeedsFile(t *testing.T) {
+	if _, err := NewTagsProvider().FetchLyrics(context.Background(), Track{Artist: "A", Title: "T"}); err == nil {
+		t.Error("FetchLyrics() without a file path succeeded")
+	}
+}
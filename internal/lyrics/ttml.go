@@ -0,0 +1,231 @@
+package lyrics
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseTTML parses TTML lyrics such as the ones exported from Apple Music.
+// Every <p> becomes a Line, timed <span>s become its Words and ttm:agent
+// references are resolved to the agent's display name when the document
+// declares one. Span times are treated as absolute, which is what Apple and
+// most exporters produce.
+func ParseTTML(content string) ([]Line, error) {
+	dec := xml.NewDecoder(strings.NewReader(content))
+	dec.Strict = false
+
+	agentNames := map[string]string{}
+	var agentID string
+	var inAgentName bool
+	var divAgent string
+
+	var lines []Line
+	var current *Line
+	var text strings.Builder
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ttml: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "agent":
+				agentID = ttmlAttr(t, "id")
+			case "name":
+				inAgentName = agentID != ""
+			case "div":
+				divAgent = ttmlAttr(t, "agent")
+			case "p":
+				begin, _ := parseTTMLTime(ttmlAttr(t, "begin"))
+				end, _ := parseTTMLTime(ttmlAttr(t, "end"))
+				agent := ttmlAttr(t, "agent")
+				if agent == "" {
+					agent = divAgent
+				}
+				current = &Line{Timestamp: begin, End: end, Agent: agent}
+				text.Reset()
+			case "span":
+				if current == nil {
+					continue
+				}
+				begin, ok := parseTTMLTime(ttmlAttr(t, "begin"))
+				if !ok {
+					continue
+				}
+				end, _ := parseTTMLTime(ttmlAttr(t, "end"))
+				current.Words = append(current.Words, Word{Timestamp: begin, End: end})
+			case "br":
+				if current != nil {
+					text.WriteString(" ")
+					appendToLastWord(current, " ")
+				}
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "agent":
+				agentID = ""
+			case "name":
+				inAgentName = false
+			case "div":
+				divAgent = ""
+			case "p":
+				if current == nil {
+					continue
+				}
+				finishTTMLLine(current, text.String())
+				if current.Text != "" {
+					lines = append(lines, *current)
+				}
+				current = nil
+			}
+
+		case xml.CharData:
+			if inAgentName {
+				agentNames[agentID] += strings.TrimSpace(string(t))
+				continue
+			}
+			if current == nil {
+				continue
+			}
+			text.Write(t)
+			appendToLastWord(current, string(t))
+		}
+	}
+
+	for i := range lines {
+		if name, ok := agentNames[lines[i].Agent]; ok && name != "" {
+			lines[i].Agent = name
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no lyrics found in ttml")
+	}
+	return lines, nil
+}
+
+func ttmlAttr(el xml.StartElement, local string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func appendToLastWord(line *Line, s string) {
+	if len(line.Words) > 0 {
+		line.Words[len(line.Words)-1].Text += s
+	}
+}
+
+// finishTTMLLine normalizes whitespace the way a TTML renderer would and
+// drops empty words left behind by container spans (e.g. background vocals).
+func finishTTMLLine(line *Line, raw string) {
+	var words []Word
+	for _, w := range line.Words {
+		w.Text = collapseSpaces(w.Text)
+		if strings.TrimSpace(w.Text) == "" {
+			if len(words) > 0 && w.Text != "" && !strings.HasSuffix(words[len(words)-1].Text, " ") {
+				words[len(words)-1].Text += " "
+			}
+			continue
+		}
+		words = append(words, w)
+	}
+
+	if len(words) == 0 {
+		line.Words = nil
+		line.Text = strings.TrimSpace(collapseSpaces(raw))
+		return
+	}
+
+	words[0].Text = strings.TrimLeft(words[0].Text, " ")
+	words[len(words)-1].Text = strings.TrimRight(words[len(words)-1].Text, " ")
+
+	var sb strings.Builder
+	for _, w := range words {
+		sb.WriteString(w.Text)
+	}
+	line.Words = words
+	line.Text = sb.String()
+	if line.Timestamp == 0 {
+		line.Timestamp = words[0].Timestamp
+	}
+}
+
+func collapseSpaces(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			if !space {
+				sb.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// parseTTMLTime parses TTML clock times ("01:02.345", "00:01:02.345") and
+// offset times ("62.345s", "1500ms", "1m").
+func parseTTMLTime(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+
+	if strings.Contains(s, ":") {
+		parts := strings.Split(s, ":")
+		if len(parts) > 3 {
+			// hh:mm:ss:frames, frames are dropped
+			parts = parts[:3]
+		}
+		total := 0.0
+		for _, p := range parts {
+			v, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return 0, false
+			}
+			total = total*60 + v
+		}
+		return total, true
+	}
+
+	units := []struct {
+		suffix string
+		scale  float64
+	}{
+		{"ms", 0.001},
+		{"h", 3600},
+		{"m", 60},
+		{"s", 1},
+	}
+	scale := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSuffix(s, u.suffix)
+			scale = u.scale
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v * scale, true
+}
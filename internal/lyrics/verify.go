@@ -0,0 +1,119 @@
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"lyrics-tui/internal/httpclient"
+)
+
+// Verification states of AI lyrics.
+const (
+	// Verified lyrics are close to lyrics found elsewhere.
+	Verified = "verified"
+	// Unverified lyrics had nothing to be compared with.
+	Unverified = "unverified"
+)
+
+const (
+	// minVerifiedScore is the lowest similarity at which AI lyrics count
+	// as the song's.
+	minVerifiedScore = 0.5
+	// minLineScore is the lowest token overlap at which two lines count
+	// as the same line.
+	minLineScore = 0.8
+)
+
+// VerifyAI looks the song the AI identified for a cached entry up through
+// the chain without the AI. Found lyrics replace the AI's in the cache, and
+// replaced is set; they are Verified when the AI's lyrics were close to
+// them. When no other source has the song, the entry is marked Unverified.
+func (s *Service) VerifyAI(ctx context.Context, artist, title string) (song *Song, replaced bool, err error) {
+	cached, err := s.cache.Load(artist, title)
+	if err != nil {
+		return nil, false, err
+	}
+	if cached.Source != ProviderAI {
+		return nil, false, fmt.Errorf("lyrics of %s - %s were not written by ai", artist, title)
+	}
+
+	track := Track{Artist: artist, Title: title, Duration: cached.Duration}
+	if cached.IdentifiedArtist != "" && cached.IdentifiedTitle != "" {
+		track.Artist, track.Title = cached.IdentifiedArtist, cached.IdentifiedTitle
+	}
+	found, err := s.fetchChain(ctx, track, false)
+	if ctx.Err() != nil || errors.Is(err, httpclient.ErrOffline) {
+		return nil, false, err
+	}
+	if err != nil {
+		cached.Verification = Unverified
+		return cached.song(), false, s.cache.Save(cached)
+	}
+
+	if lyricSimilarity(cached.Lyrics, songText(found)) >= minVerifiedScore {
+		found.Verification = Verified
+	}
+	if err := s.saveToCache(artist, title, found, cached.Offset); err != nil {
+		return nil, false, err
+	}
+	return found, true, nil
+}
+
+// songText is the song's lyrics as plain text, one line per line.
+func songText(song *Song) string {
+	if !song.HasSyncedLyrics {
+		return song.Lyrics
+	}
+	lines := make([]string, len(song.SyncedLyrics))
+	for i, line := range song.SyncedLyrics {
+		lines[i] = line.Text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lyricSimilarity scores how alike two lyrics are line by line, from 0 to
+// 1: the balance between the share of a's lines found in b and the share
+// of b's lines found in a. Empty lines and section headers are ignored.
+func lyricSimilarity(a, b string) float64 {
+	la, lb := lyricLines(a), lyricLines(b)
+	if len(la) == 0 || len(lb) == 0 {
+		return 0
+	}
+	precision := matchedShare(la, lb)
+	recall := matchedShare(lb, la)
+	if precision+recall == 0 {
+		return 0
+	}
+	return 2 * precision * recall / (precision + recall)
+}
+
+// matchedShare is the share of lines that have a close line in other.
+func matchedShare(lines, other [][]string) float64 {
+	matched := 0
+	for _, line := range lines {
+		for _, o := range other {
+			if tokenOverlap(line, o) >= minLineScore {
+				matched++
+				break
+			}
+		}
+	}
+	return float64(matched) / float64(len(lines))
+}
+
+// lyricLines splits lyrics into the tokens of each line.
+func lyricLines(lyrics string) [][]string {
+	var lines [][]string
+	for _, line := range strings.Split(lyrics, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			continue
+		}
+		if tokens := strings.Fields(normalizeLyric(line)); len(tokens) > 0 {
+			lines = append(lines, tokens)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,148 @@
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestLyricSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{"same", "Is this the real life\nIs this just fantasy", "Is this the real life\nIs this just fantasy", 1},
+		{"case, punctuation and spacing", "Is this the real life?\n\nIs this just fantasy?", "[Verse 1]\nis this  the REAL life\nis this just fantasy", 1},
+		{"section headers ignored", "[Chorus]\nline one here\n[Chorus]", "line one here", 1},
+		{"different song", "Is this the real life\nIs this just fantasy", "Yesterday all my troubles seemed so far away", 0},
+		{"half the lines", "one two three\nfour five six", "one two three\nseven eight nine", 0.5},
+		{"a subset", "one two three\nfour five six\nseven eight nine\nten eleven twelve", "one two three\nfour five six", 2 * 0.5 * 1 / 1.5},
+		{"close lines", "and i will always love you", "and i will always love you too", 1},
+		{"loose lines", "and i will always love you", "i love you", 0},
+		{"empty", "", "one two three", 0},
+		{"only headers", "[Intro]", "[Intro]", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lyricSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("lyricSimilarity() = %v, want %v", got, tt.want)
+			}
+			if got := lyricSimilarity(tt.b, tt.a); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("lyricSimilarity() reversed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// songProvider has the lyrics of a single song.
+type songProvider struct {
+	artist, title, lyrics string
+	asked                 []Track
+}
+
+func (p *songProvider) FetchLyrics(ctx context.Context, track Track) (string, error) {
+	p.asked = append(p.asked, track)
+	if track.Artist != p.artist || track.Title != p.title {
+		return "", errors.New("not found")
+	}
+	return p.lyrics, nil
+}
+
+func (p *songProvider) FetchSynced(ctx context.Context, track Track) ([]Line, error) {
+	return nil, errors.New("no synced lyrics")
+}
+
+func TestVerifyAI(t *testing.T) {
+	const aiLyrics = "Is this the real life\nIs this just fantasy\nCaught in a landslide"
+	tests := []struct {
+		name             string
+		cached           *Song
+		found            *songProvider
+		wantReplaced     bool
+		wantLyrics       string
+		wantVerification string
+		wantAsked        Track
+	}{
+		{
+			name:             "verified",
+			cached:           &Song{Lyrics: aiLyrics, IdentifiedArtist: "Queen", IdentifiedTitle: "Bohemian Rhapsody"},
+			found:            &songProvider{artist: "Queen", title: "Bohemian Rhapsody", lyrics: "Is this the real life?\nIs this just fantasy?\nCaught in a landslide,\nNo escape from reality"},
+			wantReplaced:     true,
+			wantLyrics:       "Is this the real life?\nIs this just fantasy?\nCaught in a landslide,\nNo escape from reality",
+			wantVerification: Verified,
+			wantAsked:        Track{Artist: "Queen", Title: "Bohemian Rhapsody"},
+		},
+		{
+			name:         "replaced",
+			cached:       &Song{Lyrics: aiLyrics, IdentifiedArtist: "Queen", IdentifiedTitle: "Bohemian Rhapsody"},
+			found:        &songProvider{artist: "Queen", title: "Bohemian Rhapsody", lyrics: "Mama, just killed a man\nPut a gun against his head"},
+			wantReplaced: true,
+			wantLyrics:   "Mama, just killed a man\nPut a gun against his head",
+			wantAsked:    Track{Artist: "Queen", Title: "Bohemian Rhapsody"},
+		},
+		{
+			name:             "unverified",
+			cached:           &Song{Lyrics: aiLyrics, IdentifiedArtist: "Queen", IdentifiedTitle: "Bohemian Rhapsody"},
+			found:            &songProvider{artist: "queen", title: "bohemian rhapsody typed", lyrics: aiLyrics},
+			wantLyrics:       aiLyrics,
+			wantVerification: Unverified,
+			wantAsked:        Track{Artist: "Queen", Title: "Bohemian Rhapsody"},
+		},
+		{
+			name:             "identified before it was recorded",
+			cached:           &Song{Lyrics: aiLyrics},
+			found:            &songProvider{artist: "queen", title: "bohemian rhapsody typed", lyrics: aiLyrics},
+			wantReplaced:     true,
+			wantLyrics:       aiLyrics,
+			wantVerification: Verified,
+			wantAsked:        Track{Artist: "queen", Title: "bohemian rhapsody typed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, []string{"found"}, map[string]Provider{"found": tt.found})
+			tt.cached.Source = ProviderAI
+			tt.cached.Duration = 354
+			if err := s.saveToCache("queen", "bohemian rhapsody typed", tt.cached, 1.5); err != nil {
+				t.Fatal(err)
+			}
+
+			song, replaced, err := s.VerifyAI(context.Background(), "queen", "bohemian rhapsody typed")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if replaced != tt.wantReplaced || song.Lyrics != tt.wantLyrics || song.Verification != tt.wantVerification {
+				t.Errorf("VerifyAI() = %q (%q), replaced %v; want %q (%q), replaced %v", song.Lyrics, song.Verification, replaced, tt.wantLyrics, tt.wantVerification, tt.wantReplaced)
+			}
+			tt.wantAsked.Duration = 354
+			if len(tt.found.asked) != 1 || tt.found.asked[0] != tt.wantAsked {
+				t.Errorf("looked up %+v, want %+v", tt.found.asked, tt.wantAsked)
+			}
+
+			cached, err := s.LoadFromCache("queen", "bohemian rhapsody typed")
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantSource := ProviderAI
+			if tt.wantReplaced {
+				wantSource = "found"
+			}
+			if cached.Lyrics != tt.wantLyrics || cached.Source != wantSource || cached.Verification != tt.wantVerification || cached.Offset != 1.5 {
+				t.Errorf("cached %q from %q (%q) with offset %v", cached.Lyrics, cached.Source, cached.Verification, cached.Offset)
+			}
+		})
+	}
+}
+
+func TestVerifyAIOnlyChecksAI(t *testing.T) {
+	s := newTestService(t, nil, nil)
+	if err := s.saveToCache("A", "T", &Song{Lyrics: "lyrics", Source: "found"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.VerifyAI(context.Background(), "A", "T"); err == nil {
+		t.Error("VerifyAI() checked lyrics not written by ai")
+	}
+}
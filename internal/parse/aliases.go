@@ -0,0 +1,146 @@
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alias remembers what a provider identified a query as, so the same query
+// never needs a model again. Either Query is the raw query typed by the
+// user, or RawArtist and RawTitle are the tags reported by the player.
+// Provider is the ID of the provider that identified it, or "user" once
+// corrected by hand.
+type Alias struct {
+	Query     string `json:"query,omitempty"`
+	RawArtist string `json:"rawArtist,omitempty"`
+	RawTitle  string `json:"rawTitle,omitempty"`
+
+	Artist     string     `json:"artist"`
+	Title      string     `json:"title"`
+	Provider   ProviderID `json:"provider"`
+	Identified time.Time  `json:"identified"`
+}
+
+// Raw describes what was identified, for display.
+func (a Alias) Raw() string {
+	if a.Query != "" {
+		return a.Query
+	}
+	return a.RawArtist + " - " + a.RawTitle
+}
+
+func (a Alias) key() string {
+	if a.Query != "" {
+		return "q:" + normalizeAlias(a.Query)
+	}
+	return "t:" + normalizeAlias(a.RawArtist) + "\x00" + normalizeAlias(a.RawTitle)
+}
+
+func normalizeAlias(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// Aliases is the persistent table of identified queries, stored as a
+// single JSON file.
+type Aliases struct {
+	path string
+
+	mu      sync.Mutex
+	entries []Alias
+}
+
+// NewAliases opens the alias table stored at path, starting empty if it
+// does not exist yet.
+func NewAliases(path string) *Aliases {
+	a := &Aliases{path: path}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &a.entries)
+	}
+	return a
+}
+
+// Lookup finds the alias for the player's tags when rawArtist and rawTitle
+// are set, or else for a typed query.
+func (a *Aliases) Lookup(query, rawArtist, rawTitle string) (Alias, bool) {
+	raw := Alias{Query: query}
+	if rawArtist != "" && rawTitle != "" {
+		raw = Alias{RawArtist: rawArtist, RawTitle: rawTitle}
+	}
+	key := raw.key()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, entry := range a.entries {
+		if entry.key() == key {
+			return entry, true
+		}
+	}
+	return Alias{}, false
+}
+
+// Set adds alias, replacing any alias for the same query or tags.
+func (a *Aliases) Set(alias Alias) error {
+	if alias.Identified.IsZero() {
+		alias.Identified = time.Now()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, entry := range a.entries {
+		if entry.key() == alias.key() {
+			a.entries[i] = alias
+			return a.save()
+		}
+	}
+	a.entries = append(a.entries, alias)
+	return a.save()
+}
+
+// Remove drops the alias for the same query or tags as alias.
+func (a *Aliases) Remove(alias Alias) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	kept := a.entries[:0]
+	for _, entry := range a.entries {
+		if entry.key() != alias.key() {
+			kept = append(kept, entry)
+		}
+	}
+	a.entries = kept
+	return a.save()
+}
+
+// List returns every alias, most recently identified first.
+func (a *Aliases) List() []Alias {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	list := append([]Alias(nil), a.entries...)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Identified.After(list[j].Identified)
+	})
+	return list
+}
+
+func (a *Aliases) save() error {
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return fmt.Errorf("failed to create aliases dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(a.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal aliases: %w", err)
+	}
+
+	if err := os.WriteFile(a.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write aliases: %w", err)
+	}
+	return nil
+}
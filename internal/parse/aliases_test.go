@@ -0,0 +1,124 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAliasKey(t *testing.T) {
+	tests := []struct {
+		alias Alias
+		want  string
+	}{
+		{Alias{Query: "Queen Bohemian Rhapsody"}, "q:queen bohemian rhapsody"},
+		{Alias{Query: "  QUEEN\t bohemian \n rhapsody "}, "q:queen bohemian rhapsody"},
+		{Alias{RawArtist: " Queen ", RawTitle: "Bohemian  Rhapsody"}, "t:queen\x00bohemian rhapsody"},
+		{Alias{Query: "queen", RawArtist: "Queen", RawTitle: "Bohemian Rhapsody"}, "q:queen"},
+		// tags don't collide with a query of the same text
+		{Alias{RawArtist: "queen bohemian", RawTitle: "rhapsody"}, "t:queen bohemian\x00rhapsody"},
+	}
+	for _, tt := range tests {
+		if got := tt.alias.key(); got != tt.want {
+			t.Errorf("%+v.key() = %q, want %q", tt.alias, got, tt.want)
+		}
+	}
+}
+
+func TestAliasesLookup(t *testing.T) {
+	aliases := NewAliases(filepath.Join(t.TempDir(), "aliases.json"))
+	if err := aliases.Set(Alias{Query: "bohemian rhapsody", Artist: "Queen", Title: "Bohemian Rhapsody", Provider: ProviderOllama}); err != nil {
+		t.Fatal(err)
+	}
+	if err := aliases.Set(Alias{RawArtist: "Queen", RawTitle: "Bohemian Rhapsody (Remastered 2011)", Artist: "Queen", Title: "Bohemian Rhapsody", Provider: ProviderOllama}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                       string
+		query, rawArtist, rawTitle string
+		found                      bool
+	}{
+		{"query", "bohemian rhapsody", "", "", true},
+		{"query in another case and spacing", "  Bohemian\tRHAPSODY ", "", "", true},
+		{"other query", "bohemian", "", "", false},
+		{"tags", "", "queen", "bohemian  rhapsody (remastered 2011)", true},
+		{"tags win over the query", "bohemian rhapsody", "Queen", "Bohemian Rhapsody", false},
+		{"tags need both fields", "", "Queen", "", false},
+		{"tags are not a query", "Queen - Bohemian Rhapsody (Remastered 2011)", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alias, ok := aliases.Lookup(tt.query, tt.rawArtist, tt.rawTitle)
+			if ok != tt.found {
+				t.Fatalf("Lookup() found = %v, want %v", ok, tt.found)
+			}
+			if ok && (alias.Artist != "Queen" || alias.Title != "Bohemian Rhapsody") {
+				t.Errorf("Lookup() = %+v", alias)
+			}
+		})
+	}
+}
+
+func TestAliasesPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "aliases.json")
+	aliases := NewAliases(path)
+	if len(aliases.List()) != 0 {
+		t.Fatal("new alias table is not empty")
+	}
+
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, alias := range []Alias{
+		{Query: "bohemian", Artist: "Queen", Title: "Bohemian Rhapsody", Provider: ProviderOllama, Identified: first},
+		{Query: "yesterday", Artist: "The Beatles", Title: "Yesterday", Provider: ProviderOllama, Identified: first.Add(time.Hour)},
+		// corrects the first alias by hand
+		{Query: "Bohemian", Artist: "Queen", Title: "Bohemian Rhapsody (Live)", Provider: "user"},
+	} {
+		if err := aliases.Set(alias); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened := NewAliases(path)
+	list := reopened.List()
+	if len(list) != 2 {
+		t.Fatalf("reopened table has %d aliases, want 2: %+v", len(list), list)
+	}
+	if list[0].Title != "Bohemian Rhapsody (Live)" || list[0].Provider != "user" || list[0].Identified.IsZero() {
+		t.Errorf("newest alias = %+v, want the correction stamped with its time", list[0])
+	}
+	if list[1].Title != "Yesterday" || !list[1].Identified.Equal(first.Add(time.Hour)) {
+		t.Errorf("older alias = %+v", list[1])
+	}
+
+	if err := reopened.Remove(Alias{Query: "BOHEMIAN "}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.Lookup("bohemian", "", ""); ok {
+		t.Error("removed alias is still found")
+	}
+	if _, ok := NewAliases(path).Lookup("yesterday", "", ""); !ok {
+		t.Error("removing one alias lost another")
+	}
+	if _, ok := NewAliases(path).Lookup("bohemian", "", ""); ok {
+		t.Error("removal was not saved")
+	}
+}
+
+func TestAliasesCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	aliases := NewAliases(path)
+	if len(aliases.List()) != 0 {
+		t.Fatal("corrupt alias file was not ignored")
+	}
+	if err := aliases.Set(Alias{Query: "q", Artist: "A", Title: "T"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := NewAliases(path).Lookup("q", "", ""); !ok {
+		t.Error("alias table was not rewritten")
+	}
+}
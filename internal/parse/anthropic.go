@@ -0,0 +1,113 @@
+package parse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"lyrics-tui/internal/httpclient"
+)
+
+type AnthropicProvider struct {
+	engine
+	apiKey  string
+	model   string
+	baseURL string
+	client  *http.Client
+}
+
+func NewAnthropicProvider(apiKey, model, baseURL string, client *http.Client) *AnthropicProvider {
+	if model == "" {
+		model = "claude-sonnet-4-5"
+	}
+	p := &AnthropicProvider{apiKey: apiKey, model: model, baseURL: baseURL, client: client}
+	p.engine = newEngine(p)
+	return p
+}
+
+func (p *AnthropicProvider) Name() string          { return "Anthropic" }
+func (p *AnthropicProvider) ID() ProviderID        { return ProviderAnthropic }
+func (p *AnthropicProvider) RequiresAPIKey() bool  { return true }
+func (p *AnthropicProvider) DefaultEnvVar() string { return "ANTHROPIC_API_KEY" }
+func (p *AnthropicProvider) DefaultModel() string  { return "claude-sonnet-4-5" }
+func (p *AnthropicProvider) Model() string         { return p.model }
+
+// generate sends the request to the Messages API forcing a single tool call
+// whose input follows the answer's schema, and returns that input.
+func (p *AnthropicProvider) generate(ctx context.Context, r request) (string, Usage, error) {
+	if p.apiKey == "" {
+		return "", Usage{}, fmt.Errorf("Anthropic API key not set (set ANTHROPIC_API_KEY or configure in settings with Ctrl+O)")
+	}
+
+	body := map[string]interface{}{
+		"model":      p.model,
+		"max_tokens": r.maxTokens,
+		"messages": []map[string]string{
+			{
+				"role":    "user",
+				"content": r.prompt,
+			},
+		},
+		"tools": []map[string]interface{}{
+			{
+				"name":         r.name,
+				"description":  "Record the answer.",
+				"input_schema": r.schema(),
+			},
+		},
+		"tool_choice": map[string]string{"type": "tool", "name": r.name},
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(httpclient.AllowRetry(ctx), "POST", p.baseURL+"/messages", bytes.NewReader(jsonBody))
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", Usage{}, unanswered(fmt.Errorf("anthropic request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", Usage{}, unanswered(fmt.Errorf("failed to read response: %w", err))
+	}
+
+	if resp.StatusCode != 200 {
+		return "", Usage{}, unanswered(fmt.Errorf("anthropic returned status %d: %s", resp.StatusCode, string(respBody)))
+	}
+
+	var result struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", Usage{}, unanswered(fmt.Errorf("failed to parse anthropic response: %w", err))
+	}
+
+	for _, block := range result.Content {
+		if block.Type == "tool_use" && block.Name == r.name {
+			return string(block.Input), Usage{InputTokens: result.Usage.InputTokens, OutputTokens: result.Usage.OutputTokens}, nil
+		}
+	}
+	return "", Usage{}, unanswered(fmt.Errorf("anthropic returned no %s", r.name))
+}
@@ -0,0 +1,140 @@
+package parse
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// anthropicServer answers /messages with status and body, handing every
+// decoded request body to inspect.
+func anthropicServer(t *testing.T, status int, body string, inspect func(r *http.Request, req map[string]interface{})) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		raw, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request: %v", err)
+		}
+		var req map[string]interface{}
+		if err := json.Unmarshal(raw, &req); err != nil {
+			t.Errorf("request is not json: %v", err)
+		}
+		if inspect != nil {
+			inspect(r, req)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestAnthropicForcesToolCall(t *testing.T) {
+	const answer = `{
+		"content": [
+			{"type": "text", "text": "Looking that up."},
+			{"type": "tool_use", "id": "toolu_1", "name": "song", "input": {"artist": "Queen", "title": "Bohemian Rhapsody"}}
+		],
+		"usage": {"input_tokens": 120, "output_tokens": 30}
+	}`
+	srv, _ := anthropicServer(t, http.StatusOK, answer, func(r *http.Request, req map[string]interface{}) {
+		if r.Method != http.MethodPost || r.URL.Path != "/messages" {
+			t.Errorf("request = %s %s, want POST /messages", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "key" {
+			t.Errorf("x-api-key = %q", got)
+		}
+		if r.Header.Get("anthropic-version") == "" {
+			t.Error("no anthropic-version header")
+		}
+		if req["model"] != "claude-test" {
+			t.Errorf("model = %v", req["model"])
+		}
+
+		choice, _ := req["tool_choice"].(map[string]interface{})
+		if choice["type"] != "tool" || choice["name"] != "song" {
+			t.Errorf("tool_choice = %v, want the song tool forced", req["tool_choice"])
+		}
+		tools, _ := req["tools"].([]interface{})
+		if len(tools) != 1 {
+			t.Fatalf("tools = %v, want one", req["tools"])
+		}
+		tool := tools[0].(map[string]interface{})
+		schema, _ := tool["input_schema"].(map[string]interface{})
+		if tool["name"] != "song" || schema["type"] != "object" {
+			t.Errorf("tool = %v", tool)
+		}
+		if required, _ := json.Marshal(schema["required"]); string(required) != `["artist","title"]` {
+			t.Errorf("required = %s", required)
+		}
+		messages, _ := req["messages"].([]interface{})
+		if len(messages) != 1 || !strings.Contains(messages[0].(map[string]interface{})["content"].(string), "queen bohemian") {
+			t.Errorf("messages = %v, want the query in the prompt", req["messages"])
+		}
+	})
+
+	p := NewAnthropicProvider("key", "claude-test", srv.URL, srv.Client())
+	artist, title, err := p.Parse(context.Background(), "queen bohemian")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if artist != "Queen" || title != "Bohemian Rhapsody" {
+		t.Errorf("Parse() = %q, %q", artist, title)
+	}
+
+	text, usage, err := p.generate(context.Background(), p.parseRequest("queen bohemian"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage != (Usage{InputTokens: 120, OutputTokens: 30}) {
+		t.Errorf("usage = %+v", usage)
+	}
+	if !strings.Contains(text, `"artist": "Queen"`) {
+		t.Errorf("generate() = %q, want the tool input", text)
+	}
+}
+
+func TestAnthropicErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"error status", http.StatusBadRequest, `{"type": "error", "error": {"type": "invalid_request_error", "message": "bad model"}}`, "status 400: "},
+		{"overloaded", 529, `{"type": "error"}`, "status 529"},
+		{"no tool call", http.StatusOK, `{"content": [{"type": "text", "text": "I can't help with that."}], "usage": {}}`, "returned no song"},
+		{"other tool", http.StatusOK, `{"content": [{"type": "tool_use", "name": "lyrics", "input": {}}]}`, "returned no song"},
+		{"not json", http.StatusOK, `<html>`, "failed to parse anthropic response"},
+		{"bad tool input", http.StatusOK, `{"content": [{"type": "tool_use", "name": "song", "input": "artist"}]}`, "failed to parse song json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := anthropicServer(t, tt.status, tt.body, nil)
+			p := NewAnthropicProvider("key", "", srv.URL, srv.Client())
+			_, _, err := p.Parse(context.Background(), "query")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAnthropicMissingKey(t *testing.T) {
+	srv, calls := anthropicServer(t, http.StatusOK, `{}`, nil)
+	p := NewAnthropicProvider("", "", srv.URL, srv.Client())
+	if _, _, err := p.Parse(context.Background(), "query"); err == nil || !strings.Contains(err.Error(), "API key not set") {
+		t.Errorf("Parse() error = %v, want a missing key error", err)
+	}
+	if atomic.LoadInt32(calls) != 0 {
+		t.Error("request sent without an API key")
+	}
+}
@@ -0,0 +1,342 @@
+package parse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lyrics-tui/internal/config"
+)
+
+// Prompts are the templates every backend asks its model with. {query} is
+// replaced with the user's query, or the query is appended if missing.
+type Prompts struct {
+	Parse     string
+	Lyrics    string
+	Identify  string
+	Translate string
+}
+
+// DefaultPrompts are used for templates the config leaves empty.
+var DefaultPrompts = Prompts{
+	Parse:     "Parse this song query and extract the artist and title. If any information is missing or misspelled, use your knowledge to complete it correctly. Query: {query}",
+	Lyrics:    "Identify the song and give me the full lyrics for: {query}\nKeep empty lines between verses/chorus sections.",
+	Identify:  "Which songs have lyrics containing this fragment? List up to 5, most likely first. Fragment: {query}",
+	Translate: "Translate these song lyrics into {language}. Translate every numbered line on its own, keeping its number, and convey the meaning rather than word for word. Lines:\n{query}",
+}
+
+// PromptsFromConfig returns the configured prompt templates, falling back
+// to the defaults.
+func PromptsFromConfig(cfg *config.Config) Prompts {
+	prompts := DefaultPrompts
+	if cfg.ParsePrompt != "" {
+		prompts.Parse = cfg.ParsePrompt
+	}
+	if cfg.LyricsPrompt != "" {
+		prompts.Lyrics = cfg.LyricsPrompt
+	}
+	if cfg.IdentifyPrompt != "" {
+		prompts.Identify = cfg.IdentifyPrompt
+	}
+	if cfg.TranslatePrompt != "" {
+		prompts.Translate = cfg.TranslatePrompt
+	}
+	return prompts
+}
+
+func fillPrompt(template, query string) string {
+	if !strings.Contains(template, "{query}") {
+		return template + " " + query
+	}
+	return strings.ReplaceAll(template, "{query}", query)
+}
+
+// request is one question for a model whose answer is a JSON object of
+// string fields, or with list set, a list of them under that key.
+type request struct {
+	name      string // names the answer in schemas and tool calls
+	prompt    string
+	fields    []string
+	list      string
+	maxTokens int
+}
+
+// example is the answer's shape, for backends without native schemas.
+func (r request) example() string {
+	parts := make([]string, len(r.fields))
+	for i, f := range r.fields {
+		parts[i] = fmt.Sprintf("%q: \"...\"", f)
+	}
+	item := "{" + strings.Join(parts, ", ") + "}"
+	if r.list != "" {
+		return fmt.Sprintf("{%q: [%s, ...]}", r.list, item)
+	}
+	return item
+}
+
+// promptWithExample asks for the answer's JSON in the prompt itself.
+func (r request) promptWithExample() string {
+	return r.prompt + "\nRespond with JSON only: " + r.example()
+}
+
+// schema is the answer's JSON schema.
+func (r request) schema() map[string]interface{} {
+	properties := map[string]interface{}{}
+	for _, f := range r.fields {
+		properties[f] = map[string]string{"type": "string"}
+	}
+	item := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             r.fields,
+		"additionalProperties": false,
+	}
+	if r.list == "" {
+		return item
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			r.list: map[string]interface{}{"type": "array", "items": item},
+		},
+		"required":             []string{r.list},
+		"additionalProperties": false,
+	}
+}
+
+// backend is what a provider supplies to the engine: a way to put a request
+// to its model and get the JSON answer back as text, along with the tokens
+// it cost.
+type backend interface {
+	generate(ctx context.Context, r request) (string, Usage, error)
+}
+
+// streamingBackend can also deliver the answer as it is written.
+type streamingBackend interface {
+	backend
+	stream(ctx context.Context, r request, onText func(string)) (Usage, error)
+}
+
+// engine implements the questions every provider answers on top of its
+// backend: prompts, decoding, streaming and usage accounting live here once.
+type engine struct {
+	backend backend
+	prompts Prompts
+	ledger  *Ledger
+}
+
+func newEngine(b backend) engine {
+	return engine{backend: b, prompts: DefaultPrompts}
+}
+
+func (e *engine) setPrompts(prompts Prompts) {
+	e.prompts = prompts
+}
+
+func (e *engine) setLedger(ledger *Ledger) {
+	e.ledger = ledger
+}
+
+func (e *engine) parseRequest(query string) request {
+	return request{name: "song", prompt: fillPrompt(e.prompts.Parse, query), fields: []string{"artist", "title"}, maxTokens: 256}
+}
+
+func (e *engine) lyricsRequest(query string) request {
+	return request{name: "lyrics", prompt: fillPrompt(e.prompts.Lyrics, query), fields: []string{"artist", "song", "lyrics"}, maxTokens: 8192}
+}
+
+func (e *engine) identifyRequest(fragment string) request {
+	return request{name: "songs", prompt: fillPrompt(e.prompts.Identify, fragment), fields: []string{"artist", "title"}, list: "songs", maxTokens: 1024}
+}
+
+// translateRequest asks for the lines, numbered from 1, in language. Each
+// translated line comes back with its number so that lines the model skips
+// or merges don't shift the rest.
+func (e *engine) translateRequest(lines []string, language string) request {
+	var numbered strings.Builder
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			fmt.Fprintf(&numbered, "%d: %s\n", i+1, line)
+		}
+	}
+	template := strings.ReplaceAll(e.prompts.Translate, "{language}", language)
+	return request{name: "translation", prompt: fillPrompt(template, strings.TrimSpace(numbered.String())), fields: []string{"line", "text"}, list: "lines", maxTokens: 8192}
+}
+
+func (e *engine) Parse(ctx context.Context, query string) (string, string, error) {
+	var song parsedSong
+	if err := e.ask(ctx, e.parseRequest(query), &song); err != nil {
+		return "", "", err
+	}
+	return song.Artist, song.Title, nil
+}
+
+func (e *engine) FetchLyrics(ctx context.Context, query string) (string, string, string, error) {
+	var lr lyricsResult
+	if err := e.ask(ctx, e.lyricsRequest(query), &lr); err != nil {
+		return "", "", "", err
+	}
+	return lr.Artist, lr.Song, lr.Lyrics, nil
+}
+
+func (e *engine) Identify(ctx context.Context, fragment string) ([]Candidate, error) {
+	var answer struct {
+		Songs []parsedSong `json:"songs"`
+	}
+	if err := e.ask(ctx, e.identifyRequest(fragment), &answer); err != nil {
+		return nil, err
+	}
+	candidates := make([]Candidate, 0, len(answer.Songs))
+	for _, song := range answer.Songs {
+		if song.Artist != "" && song.Title != "" {
+			candidates = append(candidates, Candidate{Artist: song.Artist, Title: song.Title})
+		}
+	}
+	return candidates, nil
+}
+
+// Translate translates lyrics into language line by line. The result has
+// one entry per line; empty lines, and lines the model left out, are empty.
+func (e *engine) Translate(ctx context.Context, lines []string, language string) ([]string, error) {
+	var answer struct {
+		Lines []struct {
+			Line string `json:"line"`
+			Text string `json:"text"`
+		} `json:"lines"`
+	}
+	if err := e.ask(ctx, e.translateRequest(lines, language), &answer); err != nil {
+		return nil, err
+	}
+	translated := make([]string, len(lines))
+	for _, line := range answer.Lines {
+		n, err := strconv.Atoi(strings.TrimSpace(line.Line))
+		if err != nil || n < 1 || n > len(lines) || strings.TrimSpace(lines[n-1]) == "" {
+			continue
+		}
+		translated[n-1] = strings.TrimSpace(line.Text)
+	}
+	return translated, nil
+}
+
+// StreamLyrics is FetchLyrics reporting the answer as it arrives. Backends
+// that can't stream report it once, complete.
+func (e *engine) StreamLyrics(ctx context.Context, query string, onPartial func(artist, title, lyrics string)) (string, string, string, error) {
+	sb, ok := e.backend.(streamingBackend)
+	if !ok {
+		artist, title, lyrics, err := e.FetchLyrics(ctx, query)
+		if err == nil {
+			onPartial(artist, title, lyrics)
+		}
+		return artist, title, lyrics, err
+	}
+
+	if err := e.allow(); err != nil {
+		return "", "", "", err
+	}
+	r := e.lyricsRequest(query)
+	s := &lyricsStream{onPartial: onPartial}
+	usage, err := sb.stream(ctx, r, s.add)
+	if err != nil {
+		return "", "", "", err
+	}
+	if err := e.record(usage); err != nil {
+		return "", "", "", err
+	}
+	var lr lyricsResult
+	if err := decodeAnswer(s.text.String(), r.name, &lr); err != nil {
+		return "", "", "", err
+	}
+	return lr.Artist, lr.Song, lr.Lyrics, nil
+}
+
+func (e *engine) ask(ctx context.Context, r request, v interface{}) error {
+	if err := e.allow(); err != nil {
+		return err
+	}
+	text, usage, err := e.backend.generate(ctx, r)
+	if err != nil {
+		return err
+	}
+	if err := e.record(usage); err != nil {
+		return err
+	}
+	return decodeAnswer(text, r.name, v)
+}
+
+// allow checks the daily budget before asking the model.
+func (e *engine) allow() error {
+	if e.ledger == nil {
+		return nil
+	}
+	return e.ledger.Allow()
+}
+
+// record adds an answer to the ledger under the backend's provider. An
+// answer whose usage can't be saved is not used, so that restarting can't
+// lift the budget.
+func (e *engine) record(usage Usage) error {
+	if e.ledger == nil {
+		return nil
+	}
+	if p, ok := e.backend.(interface{ ID() ProviderID }); ok {
+		return e.ledger.Record(p.ID(), usage)
+	}
+	return nil
+}
+
+type parsedSong struct {
+	Artist string `json:"artist"`
+	Title  string `json:"title"`
+}
+
+type lyricsResult struct {
+	Artist string `json:"artist"`
+	Song   string `json:"song"`
+	Lyrics string `json:"lyrics"`
+}
+
+// decodeAnswer decodes the JSON object in a model's answer into v.
+func decodeAnswer(text, name string, v interface{}) error {
+	content, err := extractJSON(text)
+	if err != nil {
+		return unanswered(fmt.Errorf("failed to parse %s json: %w", name, err))
+	}
+	if err := json.Unmarshal([]byte(content), v); err != nil {
+		return unanswered(fmt.Errorf("failed to parse %s json: %w", name, err))
+	}
+	return nil
+}
+
+// extractJSON returns the first JSON object in text, tolerating markdown
+// fences and chatter around it as produced by models without a JSON mode.
+func extractJSON(text string) (string, error) {
+	start := strings.Index(text, "{")
+	if start < 0 {
+		return "", fmt.Errorf("no json object in response")
+	}
+
+	depth := 0
+	inString, escaped := false, false
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return text[start : i+1], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unterminated json object in response")
+}
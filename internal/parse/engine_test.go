@@ -0,0 +1,129 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lyrics-tui/internal/config"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"bare", `{"artist": "Queen"}`, `{"artist": "Queen"}`},
+		{"fenced", "```json\n{\"artist\": \"Queen\"}\n```", `{"artist": "Queen"}`},
+		{"fenced without language", "```\n{\"artist\": \"Queen\"}\n```\n", `{"artist": "Queen"}`},
+		{"chatty prose", "Sure! Here is the song you asked for:\n{\"artist\": \"Queen\", \"title\": \"Bohemian Rhapsody\"}\nLet me know if you need anything else {or more}.", `{"artist": "Queen", "title": "Bohemian Rhapsody"}`},
+		{"nested objects", `{"songs": [{"artist": "A"}, {"artist": "B"}]} trailing`, `{"songs": [{"artist": "A"}, {"artist": "B"}]}`},
+		{"braces inside strings", `{"lyrics": "a } b { c }}", "x": "{"} done`, `{"lyrics": "a } b { c }}", "x": "{"}`},
+		{"escaped quotes inside strings", `{"lyrics": "she said \"}\" and left", "y": "\\"}`, `{"lyrics": "she said \"}\" and left", "y": "\\"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractJSON(tt.text)
+			if err != nil || got != tt.want {
+				t.Errorf("extractJSON() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	for _, text := range []string{"", "I don't know that song.", `{"artist": "Queen"`, `{"lyrics": "unterminated }`} {
+		if got, err := extractJSON(text); err == nil {
+			t.Errorf("extractJSON(%q) = %q, want an error", text, got)
+		}
+	}
+}
+
+func TestDecodeAnswer(t *testing.T) {
+	var song parsedSong
+	if err := decodeAnswer("```json\n{\"artist\": \"Queen\", \"title\": \"Bohemian Rhapsody\", \"year\": 1975}\n```", "song", &song); err != nil {
+		t.Fatal(err)
+	}
+	if song != (parsedSong{Artist: "Queen", Title: "Bohemian Rhapsody"}) {
+		t.Errorf("decoded %+v", song)
+	}
+
+	for _, text := range []string{"no json here", `{"artist": 5}`, `{"artist": "Queen",}`} {
+		err := decodeAnswer(text, "song", &song)
+		var ue *unansweredError
+		if !errors.As(err, &ue) {
+			t.Errorf("decodeAnswer(%q) error = %v, want unanswered", text, err)
+		} else if !strings.Contains(err.Error(), "song json") {
+			t.Errorf("decodeAnswer(%q) error = %v, want it to name the answer", text, err)
+		}
+	}
+}
+
+func TestFillPrompt(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"Find: {query}", "Find: queen bohemian"},
+		{"{query} or {query}", "queen bohemian or queen bohemian"},
+		{"Find the song", "Find the song queen bohemian"},
+		{"Find {song} by {artist}: {query}", "Find {song} by {artist}: queen bohemian"},
+		{"Find {Query}", "Find {Query} queen bohemian"},
+	}
+	for _, tt := range tests {
+		if got := fillPrompt(tt.template, "queen bohemian"); got != tt.want {
+			t.Errorf("fillPrompt(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestPromptsFromConfig(t *testing.T) {
+	if got := PromptsFromConfig(&config.Config{}); got != DefaultPrompts {
+		t.Errorf("empty config prompts = %+v, want the defaults", got)
+	}
+
+	got := PromptsFromConfig(&config.Config{ParsePrompt: "Who sang {query}?", TranslatePrompt: "To {language}: {query}"})
+	want := DefaultPrompts
+	want.Parse = "Who sang {query}?"
+	want.Translate = "To {language}: {query}"
+	if got != want {
+		t.Errorf("PromptsFromConfig() = %+v, want %+v", got, want)
+	}
+}
+
+// answerBackend answers every request with a fixed text, keeping the
+// requests it was given.
+type answerBackend struct {
+	answer   string
+	requests []request
+}
+
+func (b *answerBackend) generate(ctx context.Context, r request) (string, Usage, error) {
+	b.requests = append(b.requests, r)
+	return b.answer, Usage{}, nil
+}
+
+func TestEngineUsesConfiguredPrompts(t *testing.T) {
+	b := &answerBackend{}
+	e := newEngine(b)
+	e.setPrompts(PromptsFromConfig(&config.Config{ParsePrompt: "Who sang {query}?", TranslatePrompt: "Into {language}, keep {style}:"}))
+
+	b.answer = `{"artist": "Queen", "title": "Bohemian Rhapsody"}`
+	artist, title, err := e.Parse(context.Background(), "bohemian")
+	if err != nil || artist != "Queen" || title != "Bohemian Rhapsody" {
+		t.Errorf("Parse() = %q, %q, %v", artist, title, err)
+	}
+	if got := b.requests[0].prompt; got != "Who sang bohemian?" {
+		t.Errorf("parse prompt = %q", got)
+	}
+
+	b.answer = `{"lines": [{"line": "2", "text": " Hallo "}, {"line": "9", "text": "out of range"}, {"line": "x", "text": "not a number"}]}`
+	translated, err := e.Translate(context.Background(), []string{"", "Hello"}, "German")
+	if err != nil || !reflect.DeepEqual(translated, []string{"", "Hallo"}) {
+		t.Errorf("Translate() = %q, %v", translated, err)
+	}
+	if got := b.requests[1].prompt; got != "Into German, keep {style}: 2: Hello" {
+		t.Errorf("translate prompt = %q", got)
+	}
+}
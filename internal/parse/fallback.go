@@ -0,0 +1,211 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FallbackProvider asks its providers in order, moving on to the next one
+// when a provider can't be reached, answers with an error status or
+// doesn't follow the schema. Other errors, such as a missing API key or
+// the daily budget being used up, are returned straight away. It presents
+// itself as the first provider; the Answered methods also return the
+// provider that answered.
+type FallbackProvider struct {
+	providers []Provider
+}
+
+// NewFallbackProvider creates a provider trying providers in order. It
+// needs at least one.
+func NewFallbackProvider(providers ...Provider) *FallbackProvider {
+	return &FallbackProvider{providers: providers}
+}
+
+func (p *FallbackProvider) Name() string          { return p.providers[0].Name() }
+func (p *FallbackProvider) ID() ProviderID        { return p.providers[0].ID() }
+func (p *FallbackProvider) RequiresAPIKey() bool  { return p.providers[0].RequiresAPIKey() }
+func (p *FallbackProvider) DefaultEnvVar() string { return p.providers[0].DefaultEnvVar() }
+func (p *FallbackProvider) DefaultModel() string  { return p.providers[0].DefaultModel() }
+
+// Model is the first provider's model.
+func (p *FallbackProvider) Model() string {
+	return modelOf(p.providers[0])
+}
+
+// Providers returns the providers in the order they are tried.
+func (p *FallbackProvider) Providers() []Provider {
+	return append([]Provider(nil), p.providers...)
+}
+
+func (p *FallbackProvider) Parse(ctx context.Context, query string) (string, string, error) {
+	artist, title, _, err := p.ParseAnswered(ctx, query)
+	return artist, title, err
+}
+
+// ParseAnswered is Parse, also returning the provider that answered.
+func (p *FallbackProvider) ParseAnswered(ctx context.Context, query string) (artist, title string, answered Provider, err error) {
+	answered, err = p.try(ctx, func(provider Provider) error {
+		var err error
+		artist, title, err = provider.Parse(ctx, query)
+		return err
+	})
+	return artist, title, answered, err
+}
+
+func (p *FallbackProvider) FetchLyrics(ctx context.Context, query string) (string, string, string, error) {
+	artist, title, lyrics, _, err := p.FetchLyricsByModel(ctx, query)
+	return artist, title, lyrics, err
+}
+
+// FetchLyricsByModel is FetchLyrics, also returning the model of the
+// provider that answered.
+func (p *FallbackProvider) FetchLyricsByModel(ctx context.Context, query string) (artist, title, lyrics, model string, err error) {
+	answered, err := p.try(ctx, func(provider Provider) error {
+		var err error
+		artist, title, lyrics, err = provider.FetchLyrics(ctx, query)
+		return err
+	})
+	if err != nil {
+		return "", "", "", "", err
+	}
+	return artist, title, lyrics, modelOf(answered), nil
+}
+
+func (p *FallbackProvider) Identify(ctx context.Context, fragment string) ([]Candidate, error) {
+	var candidates []Candidate
+	_, err := p.try(ctx, func(provider Provider) error {
+		identifier, ok := provider.(Identifier)
+		if !ok {
+			return unanswered(fmt.Errorf("%s can't identify songs by their lyrics", provider.Name()))
+		}
+		var err error
+		candidates, err = identifier.Identify(ctx, fragment)
+		return err
+	})
+	return candidates, err
+}
+
+func (p *FallbackProvider) Translate(ctx context.Context, lines []string, language string) ([]string, error) {
+	var translated []string
+	_, err := p.try(ctx, func(provider Provider) error {
+		translator, ok := provider.(Translator)
+		if !ok {
+			return unanswered(fmt.Errorf("%s can't translate lyrics", provider.Name()))
+		}
+		var err error
+		translated, err = translator.Translate(ctx, lines, language)
+		return err
+	})
+	return translated, err
+}
+
+// StreamLyrics streams from the first provider that answers. A provider
+// failing midway starts the lyrics over with the next one.
+func (p *FallbackProvider) StreamLyrics(ctx context.Context, query string, onPartial func(artist, title, lyrics string)) (string, string, string, error) {
+	artist, title, lyrics, _, err := p.StreamLyricsByModel(ctx, query, onPartial)
+	return artist, title, lyrics, err
+}
+
+// StreamLyricsByModel is StreamLyrics, also returning the model of the
+// provider that answered.
+func (p *FallbackProvider) StreamLyricsByModel(ctx context.Context, query string, onPartial func(artist, title, lyrics string)) (artist, title, lyrics, model string, err error) {
+	answered, err := p.try(ctx, func(provider Provider) error {
+		var err error
+		if streamer, ok := provider.(LyricsStreamer); ok {
+			artist, title, lyrics, err = streamer.StreamLyrics(ctx, query, onPartial)
+			return err
+		}
+		artist, title, lyrics, err = provider.FetchLyrics(ctx, query)
+		if err == nil {
+			onPartial(artist, title, lyrics)
+		}
+		return err
+	})
+	if err != nil {
+		return "", "", "", "", err
+	}
+	return artist, title, lyrics, modelOf(answered), nil
+}
+
+// ListModels lists the first provider's models.
+func (p *FallbackProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	if lister, ok := p.providers[0].(ModelLister); ok {
+		return lister.ListModels(ctx)
+	}
+	return nil, nil
+}
+
+// Warm warms up the first provider's model.
+func (p *FallbackProvider) Warm(ctx context.Context) error {
+	if warmer, ok := p.providers[0].(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
+
+func (p *FallbackProvider) setPrompts(prompts Prompts) {
+	for _, provider := range p.providers {
+		if e, ok := provider.(interface{ setPrompts(Prompts) }); ok {
+			e.setPrompts(prompts)
+		}
+	}
+}
+
+func (p *FallbackProvider) setLedger(ledger *Ledger) {
+	for _, provider := range p.providers {
+		if e, ok := provider.(interface{ setLedger(*Ledger) }); ok {
+			e.setLedger(ledger)
+		}
+	}
+}
+
+// unansweredError is a provider failing to answer a question: it couldn't
+// be reached, answered with an error status or not in the schema, or
+// doesn't take that kind of question. Only these move a FallbackProvider
+// on to its next provider.
+type unansweredError struct {
+	err error
+}
+
+func (e *unansweredError) Error() string { return e.err.Error() }
+func (e *unansweredError) Unwrap() error { return e.err }
+
+func unanswered(err error) error {
+	return &unansweredError{err: err}
+}
+
+// try calls ask with each provider until one succeeds, and returns it, or
+// fails with an error other than not answering. That error is wrapped, the
+// errors of the providers tried before are listed before it.
+func (p *FallbackProvider) try(ctx context.Context, ask func(Provider) error) (Provider, error) {
+	var failures []string
+	for i, provider := range p.providers {
+		err := ask(provider)
+		if err == nil {
+			return provider, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var ue *unansweredError
+		if i < len(p.providers)-1 && errors.As(err, &ue) {
+			failures = append(failures, fmt.Sprintf("%s: %v", provider.ID(), err))
+			continue
+		}
+		if len(failures) == 0 {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s; %s: %w", strings.Join(failures, "; "), provider.ID(), err)
+	}
+	return nil, fmt.Errorf("no AI providers configured")
+}
+
+// modelOf is provider's model, empty for providers without one.
+func modelOf(provider Provider) string {
+	if m, ok := provider.(interface{ Model() string }); ok {
+		return m.Model()
+	}
+	return ""
+}
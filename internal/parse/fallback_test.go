@@ -0,0 +1,178 @@
+package parse
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type stubProvider struct {
+	id    ProviderID
+	err   error
+	model string
+	// only, when set, is the one query the provider answers
+	only  string
+	calls int32
+}
+
+func (p *stubProvider) Name() string          { return string(p.id) }
+func (p *stubProvider) ID() ProviderID        { return p.id }
+func (p *stubProvider) RequiresAPIKey() bool  { return false }
+func (p *stubProvider) DefaultEnvVar() string { return "" }
+func (p *stubProvider) DefaultModel() string  { return "" }
+func (p *stubProvider) Model() string         { return p.model }
+
+func (p *stubProvider) Parse(ctx context.Context, query string) (string, string, error) {
+	atomic.AddInt32(&p.calls, 1)
+	if err := p.answers(query); err != nil {
+		return "", "", err
+	}
+	return "Artist", string(p.id), nil
+}
+
+func (p *stubProvider) FetchLyrics(ctx context.Context, query string) (string, string, string, error) {
+	if err := p.answers(query); err != nil {
+		return "", "", "", err
+	}
+	return "Artist", query, "lyrics by " + string(p.id), nil
+}
+
+func (p *stubProvider) answers(query string) error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.only != "" && query != p.only {
+		return unanswered(fmt.Errorf("%s doesn't know %q", p.id, query))
+	}
+	return nil
+}
+
+func TestFallbackMovesOnWhenUnanswered(t *testing.T) {
+	anthropic := func(status int, body string) func(t *testing.T) Provider {
+		return func(t *testing.T) Provider {
+			srv, _ := anthropicServer(t, status, body, nil)
+			return NewAnthropicProvider("key", "", srv.URL, srv.Client())
+		}
+	}
+	tests := []struct {
+		name  string
+		first func(t *testing.T) Provider
+	}{
+		{"stub", func(t *testing.T) Provider {
+			return &stubProvider{id: "first", err: unanswered(fmt.Errorf("first returned status 503"))}
+		}},
+		{"error status", anthropic(http.StatusInternalServerError, `{}`)},
+		{"no tool call", anthropic(http.StatusOK, `{"content": []}`)},
+		{"not json", anthropic(http.StatusOK, `<html>`)},
+		{"unreachable", func(t *testing.T) Provider {
+			return NewOllamaProvider("", "http://127.0.0.1:1", http.DefaultClient)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			second := &stubProvider{id: "second"}
+			p := NewFallbackProvider(tt.first(t), second)
+
+			_, title, answered, err := p.ParseAnswered(context.Background(), "query")
+			if err != nil || title != "second" {
+				t.Fatalf("ParseAnswered() = %q, %v; want the second provider's answer", title, err)
+			}
+			if answered != second {
+				t.Errorf("answered by %v, want the second provider", answered)
+			}
+		})
+	}
+}
+
+func TestFallbackStopsOnOtherErrors(t *testing.T) {
+	budget := &stubProvider{id: "first", err: fmt.Errorf("asking first: %w", ErrBudgetExceeded)}
+	tests := []struct {
+		name    string
+		first   Provider
+		wantErr string
+	}{
+		{"budget", budget, ErrBudgetExceeded.Error()},
+		{"missing api key", NewAnthropicProvider("", "", "http://127.0.0.1:1", http.DefaultClient), "API key not set"},
+		{"other error", &stubProvider{id: "first", err: fmt.Errorf("can't tell")}, "can't tell"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			second := &stubProvider{id: "second"}
+			p := NewFallbackProvider(tt.first, second)
+
+			_, _, answered, err := p.ParseAnswered(context.Background(), "query")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseAnswered() error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if atomic.LoadInt32(&second.calls) != 0 {
+				t.Error("fell back to the second provider")
+			}
+			if answered != nil {
+				t.Errorf("answered by %v, want nil", answered)
+			}
+		})
+	}
+}
+
+func TestFallbackListsEarlierFailures(t *testing.T) {
+	p := NewFallbackProvider(
+		&stubProvider{id: "first", err: unanswered(fmt.Errorf("unreachable"))},
+		&stubProvider{id: "second", err: fmt.Errorf("API key not set")},
+		&stubProvider{id: "third"},
+	)
+	_, _, err := p.Parse(context.Background(), "query")
+	if err == nil || err.Error() != "first: unreachable; second: API key not set" {
+		t.Errorf("Parse() error = %v", err)
+	}
+}
+
+func TestFallbackReportsAnswererPerCall(t *testing.T) {
+	first := &stubProvider{id: "first", model: "small", only: "known"}
+	second := &stubProvider{id: "second", model: "large"}
+	p := NewFallbackProvider(first, second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		query, want := "known", Provider(first)
+		if i%2 == 1 {
+			query, want = "unknown", second
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, answered, err := p.ParseAnswered(context.Background(), query)
+			if err != nil || answered != want {
+				t.Errorf("ParseAnswered(%q) answered by %v, %v; want %s", query, answered, err, want.ID())
+			}
+		}()
+	}
+	wg.Wait()
+
+	tests := []struct {
+		query     string
+		wantModel string
+	}{
+		{"known", "small"},
+		{"unknown", "large"},
+	}
+	for _, tt := range tests {
+		_, _, lyrics, model, err := p.FetchLyricsByModel(context.Background(), tt.query)
+		if err != nil || model != tt.wantModel {
+			t.Errorf("FetchLyricsByModel(%q) = %q by %q, %v; want %q's", tt.query, lyrics, model, err, tt.wantModel)
+		}
+		var partial string
+		_, _, _, model, err = p.StreamLyricsByModel(context.Background(), tt.query, func(_, _, lyrics string) { partial = lyrics })
+		if err != nil || model != tt.wantModel || partial != lyrics {
+			t.Errorf("StreamLyricsByModel(%q) reported %q by %q, %v", tt.query, partial, model, err)
+		}
+	}
+	if got := p.Model(); got != "small" {
+		t.Errorf("Model() = %q, want the first provider's", got)
+	}
+}
@@ -2,22 +2,31 @@ package parse
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+
+	"lyrics-tui/internal/httpclient"
 )
 
 type GeminiProvider struct {
-	apiKey string
-	model  string
+	engine
+	apiKey  string
+	model   string
+	baseURL string
+	client  *http.Client
 }
 
-func NewGeminiProvider(apiKey, model string) *GeminiProvider {
+func NewGeminiProvider(apiKey, model, baseURL string, client *http.Client) *GeminiProvider {
 	if model == "" {
 		model = "gemini-3-pro-preview"
 	}
-	return &GeminiProvider{apiKey: apiKey, model: model}
+	p := &GeminiProvider{apiKey: apiKey, model: model, baseURL: baseURL, client: client}
+	p.engine = newEngine(p)
+	return p
 }
 
 func (p *GeminiProvider) Name() string          { return "Gemini" }
@@ -25,90 +34,120 @@ func (p *GeminiProvider) ID() ProviderID        { return ProviderGemini }
 func (p *GeminiProvider) RequiresAPIKey() bool  { return true }
 func (p *GeminiProvider) DefaultEnvVar() string { return "GEMINI_API_KEY" }
 func (p *GeminiProvider) DefaultModel() string  { return "gemini-3-pro-preview" }
+func (p *GeminiProvider) Model() string         { return p.model }
 
-func (p *GeminiProvider) Parse(query string) (string, string, error) {
-	if p.apiKey == "" {
-		return "", "", fmt.Errorf("Gemini API key not set (set GEMINI_API_KEY or configure in settings with Ctrl+O)")
-	}
-
-	body := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]string{
-					{
-						"text": fmt.Sprintf("Parse this song query and extract the artist and title. If any information is missing or misspelled, use your knowledge to complete it correctly. Respond with JSON only: {\"artist\": \"...\", \"title\": \"...\"}. Query: %s", query),
-					},
-				},
-			},
-		},
-		"generationConfig": map[string]interface{}{
-			"responseMimeType": "application/json",
-			"responseSchema": map[string]interface{}{
-				"type": "OBJECT",
-				"properties": map[string]interface{}{
-					"artist": map[string]string{"type": "STRING"},
-					"title":  map[string]string{"type": "STRING"},
-				},
-				"required": []string{"artist", "title"},
-			},
-		},
+func (p *GeminiProvider) generate(ctx context.Context, r request) (string, Usage, error) {
+	resp, err := p.post(ctx, r, false)
+	if err != nil {
+		return "", Usage{}, err
 	}
+	defer resp.Body.Close()
 
-	jsonBody, err := json.Marshal(body)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to marshal request: %w", err)
+	var result geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", Usage{}, unanswered(fmt.Errorf("failed to parse gemini response: %w", err))
 	}
 
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", p.model, p.apiKey)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonBody))
-	if err != nil {
-		return "", "", fmt.Errorf("failed to create request: %w", err)
+	text := result.text()
+	if text == "" {
+		return "", Usage{}, unanswered(fmt.Errorf("gemini returned no content"))
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return text, result.usage(), nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// stream is generate with the answer streamed as server-sent events. Every
+// chunk carries the usage so far.
+func (p *GeminiProvider) stream(ctx context.Context, r request, onText func(string)) (Usage, error) {
+	resp, err := p.post(ctx, r, true)
 	if err != nil {
-		return "", "", fmt.Errorf("gemini request failed: %w", err)
+		return Usage{}, err
 	}
 	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to read response: %w", err)
-	}
+	var usage Usage
+	err = readSSE(resp.Body, func(data []byte) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return unanswered(fmt.Errorf("failed to parse gemini stream: %w", err))
+		}
+		if chunk.UsageMetadata != nil {
+			usage = chunk.usage()
+		}
+		onText(chunk.text())
+		return nil
+	})
+	return usage, err
+}
 
-	if resp.StatusCode != 200 {
-		return "", "", fmt.Errorf("gemini returned status %d: %s", resp.StatusCode, string(respBody))
-	}
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text    string `json:"text"`
+				Thought bool   `json:"thought"`
+			} `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	} `json:"usageMetadata"`
+}
 
-	var result struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", "", fmt.Errorf("failed to parse gemini response: %w", err)
+// usage counts thoughts as output, as they are billed like it.
+func (r geminiResponse) usage() Usage {
+	if r.UsageMetadata == nil {
+		return Usage{}
 	}
+	m := r.UsageMetadata
+	return Usage{InputTokens: m.PromptTokenCount, OutputTokens: m.CandidatesTokenCount + m.ThoughtsTokenCount}
+}
 
-	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
-		return "", "", fmt.Errorf("gemini returned no content")
+// text joins the answer's parts, leaving out thoughts.
+func (r geminiResponse) text() string {
+	if len(r.Candidates) == 0 {
+		return ""
 	}
-
-	var song parsedSong
-	if err := json.Unmarshal([]byte(result.Candidates[0].Content.Parts[0].Text), &song); err != nil {
-		return "", "", fmt.Errorf("failed to parse song json: %w", err)
+	var sb strings.Builder
+	for _, part := range r.Candidates[0].Content.Parts {
+		if !part.Thought {
+			sb.WriteString(part.Text)
+		}
 	}
+	return sb.String()
+}
 
-	return song.Artist, song.Title, nil
+// geminiSchema is the request's answer schema in Gemini's dialect, which
+// spells types in capitals and orders properties explicitly.
+func geminiSchema(r request) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for _, f := range r.fields {
+		properties[f] = map[string]string{"type": "STRING"}
+	}
+	item := map[string]interface{}{
+		"type":             "OBJECT",
+		"properties":       properties,
+		"required":         r.fields,
+		"propertyOrdering": r.fields,
+	}
+	if r.list == "" {
+		return item
+	}
+	return map[string]interface{}{
+		"type": "OBJECT",
+		"properties": map[string]interface{}{
+			r.list: map[string]interface{}{"type": "ARRAY", "items": item},
+		},
+		"required": []string{r.list},
+	}
 }
 
-func (p *GeminiProvider) FetchLyrics(query string) (string, string, string, error) {
+// post asks the model to answer r, streamed as server-sent events if
+// stream is set, and returns the response once it has a 200 status.
+func (p *GeminiProvider) post(ctx context.Context, r request, stream bool) (*http.Response, error) {
 	if p.apiKey == "" {
-		return "", "", "", fmt.Errorf("Gemini API key not set (set GEMINI_API_KEY or configure in settings with Ctrl+O)")
+		return nil, fmt.Errorf("Gemini API key not set (set GEMINI_API_KEY or configure in settings with Ctrl+O)")
 	}
 
 	body := map[string]interface{}{
@@ -116,22 +155,14 @@ func (p *GeminiProvider) FetchLyrics(query string) (string, string, string, erro
 			{
 				"parts": []map[string]string{
 					{
-						"text": fmt.Sprintf("Identify the song and give me the full lyrics for: %s\nKeep empty lines between verses/chorus sections.", query),
+						"text": r.prompt,
 					},
 				},
 			},
 		},
 		"generationConfig": map[string]interface{}{
 			"responseMimeType": "application/json",
-			"responseSchema": map[string]interface{}{
-				"type": "OBJECT",
-				"properties": map[string]interface{}{
-					"artist": map[string]string{"type": "STRING"},
-					"song":   map[string]string{"type": "STRING"},
-					"lyrics": map[string]string{"type": "STRING"},
-				},
-				"required": []string{"artist", "song", "lyrics"},
-			},
+			"responseSchema":   geminiSchema(r),
 			"thinkingConfig": map[string]interface{}{
 				"thinkingLevel": "MINIMAL",
 			},
@@ -140,52 +171,29 @@ func (p *GeminiProvider) FetchLyrics(query string) (string, string, string, erro
 
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", p.model, p.apiKey)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonBody))
-	if err != nil {
-		return "", "", "", fmt.Errorf("failed to create request: %w", err)
+	method := "generateContent?"
+	if stream {
+		method = "streamGenerateContent?alt=sse&"
 	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
+	url := fmt.Sprintf("%s/models/%s:%skey=%s", p.baseURL, p.model, method, p.apiKey)
+	req, err := http.NewRequestWithContext(httpclient.AllowRetry(ctx), "POST", url, bytes.NewReader(jsonBody))
 	if err != nil {
-		return "", "", "", fmt.Errorf("gemini request failed: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	defer resp.Body.Close()
+	req.Header.Set("Content-Type", "application/json")
 
-	respBody, err := io.ReadAll(resp.Body)
+	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to read response: %w", err)
+		return nil, unanswered(fmt.Errorf("gemini request failed: %w", err))
 	}
 
 	if resp.StatusCode != 200 {
-		return "", "", "", fmt.Errorf("gemini returned status %d: %s", resp.StatusCode, string(respBody))
-	}
-
-	var result struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, unanswered(fmt.Errorf("gemini returned status %d: %s", resp.StatusCode, string(respBody)))
 	}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", "", "", fmt.Errorf("failed to parse gemini response: %w", err)
-	}
-
-	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
-		return "", "", "", fmt.Errorf("gemini returned no content")
-	}
-
-	var lr lyricsResult
-	if err := json.Unmarshal([]byte(result.Candidates[0].Content.Parts[0].Text), &lr); err != nil {
-		return "", "", "", fmt.Errorf("failed to parse lyrics json: %w", err)
-	}
-
-	return lr.Artist, lr.Song, lr.Lyrics, nil
+	return resp, nil
 }
@@ -0,0 +1,112 @@
+package parse
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// HeuristicProvider splits queries into artist and title by their shape,
+// without asking a model. It handles "Artist - Title", "Title by Artist",
+// quoted titles and featured artists, but can't fix typos or find lyrics.
+type HeuristicProvider struct{}
+
+func NewHeuristicProvider() *HeuristicProvider {
+	return &HeuristicProvider{}
+}
+
+func (p *HeuristicProvider) Name() string          { return "Heuristic" }
+func (p *HeuristicProvider) ID() ProviderID        { return ProviderHeuristic }
+func (p *HeuristicProvider) RequiresAPIKey() bool  { return false }
+func (p *HeuristicProvider) DefaultEnvVar() string { return "" }
+func (p *HeuristicProvider) DefaultModel() string  { return "" }
+
+func (p *HeuristicProvider) Parse(ctx context.Context, query string) (string, string, error) {
+	artist, title, ok := SplitQuery(query)
+	if !ok {
+		return "", "", fmt.Errorf("can't tell the artist from the title in %q, try \"Artist - Title\"", query)
+	}
+	return artist, title, nil
+}
+
+func (p *HeuristicProvider) FetchLyrics(ctx context.Context, query string) (string, string, string, error) {
+	return "", "", "", fmt.Errorf("the heuristic provider can't write lyrics")
+}
+
+var (
+	separators = []string{" - ", " – ", " — ", " | ", " ~ "}
+	quoted     = regexp.MustCompile(`["“”]([^"“”]+)["“”]`)
+	byArtist   = regexp.MustCompile(`(?i)^(.+)\s+by\s+(.+)$`)
+	leadingBy  = regexp.MustCompile(`(?i)^by\s+`)
+	// words after "by" that end a title, as in "Stand by Me", rather than
+	// start an artist
+	titleBy = regexp.MustCompile(`(?i)^(me|you|my|your|myself|yourself|him|her|his|us|our|them|their|it|its|now)\b`)
+
+	// featured artists and release noise, which lyric databases leave out
+	// of titles
+	featBracket = regexp.MustCompile(`(?i)\s*[(\[]\s*(feat\.?|ft\.?|featuring|with)\s[^)\]]*[)\]]`)
+	featTrail   = regexp.MustCompile(`(?i)\s+(feat\.|ft\.|featuring)\s.*$`)
+	noise       = regexp.MustCompile(`(?i)\s*[(\[][^)\]]*\b(remaster(ed)?|official|lyrics?|audio|video|visuali[sz]er|explicit|hd|hq|4k)\b[^)\]]*[)\]]`)
+	remaster    = regexp.MustCompile(`(?i)\s+-\s+(\d{4}\s+)?remaster(ed)?(\s+\d{4})?(\s+version)?$`)
+	channel     = regexp.MustCompile(`(?i)(\s+-\s+topic|vevo)$`)
+)
+
+// SplitQuery splits a free-form query into artist and title, reporting
+// whether its shape gave them away. Separators win over "by"; with "by",
+// the last one separates them unless a title could end there, so "Stand by
+// Me" is left unsplit.
+func SplitQuery(query string) (artist, title string, ok bool) {
+	query = strings.TrimSpace(query)
+
+	if m := quoted.FindStringSubmatchIndex(query); m != nil {
+		title = query[m[2]:m[3]]
+		rest := strings.TrimSpace(query[:m[0]] + " " + query[m[1]:])
+		rest = strings.TrimSpace(strings.Trim(rest, "-–—|~:,"))
+		return clean(leadingBy.ReplaceAllString(rest, ""), title)
+	}
+
+	for _, sep := range separators {
+		if i := strings.Index(query, sep); i >= 0 {
+			return clean(query[:i], query[i+len(sep):])
+		}
+	}
+
+	if m := byArtist.FindStringSubmatch(query); m != nil && !titleBy.MatchString(m[2]) {
+		return clean(m[2], m[1])
+	}
+	return "", "", false
+}
+
+// CleanTags tidies the artist and title a player reports. Featured artists
+// and release noise are dropped. Video titles like "Artist - Title" are
+// split when the player's artist is that artist or a "Artist - Topic" or
+// "ArtistVEVO" channel, but not "Title - Live" style suffixes.
+func CleanTags(artist, title string) (string, string, bool) {
+	artist = strings.TrimSpace(artist)
+	isChannel := channel.MatchString(artist)
+	artist = channel.ReplaceAllString(artist, "")
+
+	for _, sep := range separators {
+		i := strings.Index(title, sep)
+		if i < 0 {
+			continue
+		}
+		left := strings.TrimSpace(title[:i])
+		if artist == "" || isChannel || strings.EqualFold(left, artist) {
+			return clean(left, title[i+len(sep):])
+		}
+		break
+	}
+	return clean(artist, title)
+}
+
+func clean(artist, title string) (string, string, bool) {
+	artist = strings.TrimSpace(featTrail.ReplaceAllString(featBracket.ReplaceAllString(artist, ""), ""))
+	title = featBracket.ReplaceAllString(title, "")
+	title = noise.ReplaceAllString(title, "")
+	title = remaster.ReplaceAllString(title, "")
+	title = featTrail.ReplaceAllString(title, "")
+	title = strings.TrimSpace(strings.Trim(title, `"“” `))
+	return artist, title, artist != "" && title != ""
+}
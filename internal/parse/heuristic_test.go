@@ -0,0 +1,62 @@
+package parse
+
+import "testing"
+
+func TestSplitQuery(t *testing.T) {
+	tests := []struct {
+		query         string
+		artist, title string
+		ok            bool
+	}{
+		{"Queen - Bohemian Rhapsody", "Queen", "Bohemian Rhapsody", true},
+		{"Queen – Bohemian Rhapsody", "Queen", "Bohemian Rhapsody", true},
+		{"Queen | Bohemian Rhapsody (Official Video)", "Queen", "Bohemian Rhapsody", true},
+		{"Bohemian Rhapsody by Queen", "Queen", "Bohemian Rhapsody", true},
+		{"Yesterday by The Beatles", "The Beatles", "Yesterday", true},
+		{"Stand by Me by Ben E. King", "Ben E. King", "Stand by Me", true},
+		{"Stand by Me", "", "", false},
+		{"stand by me", "", "", false},
+		{"Stand by Your Man", "", "", false},
+		{"Ben E. King - Stand by Me", "Ben E. King", "Stand by Me", true},
+		{`"Stand by Me" by Ben E. King`, "Ben E. King", "Stand by Me", true},
+		{`Ben E. King "Stand by Me"`, "Ben E. King", "Stand by Me", true},
+		{"Daft Punk - Get Lucky (feat. Pharrell Williams)", "Daft Punk", "Get Lucky", true},
+		{"Get Lucky by Daft Punk ft. Pharrell", "Daft Punk", "Get Lucky", true},
+		{"bohemian rhapsody", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			artist, title, ok := SplitQuery(tt.query)
+			if ok != tt.ok || (ok && (artist != tt.artist || title != tt.title)) {
+				t.Errorf("SplitQuery(%q) = %q, %q, %v; want %q, %q, %v", tt.query, artist, title, ok, tt.artist, tt.title, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCleanTags(t *testing.T) {
+	tests := []struct {
+		name                  string
+		artist, title         string
+		wantArtist, wantTitle string
+	}{
+		{"plain tags", "Ben E. King", "Stand by Me", "Ben E. King", "Stand by Me"},
+		{"remaster suffix", "Ben E. King", "Stand by Me - 2008 Remastered Version", "Ben E. King", "Stand by Me"},
+		{"artist in video title", "Queen", "Queen - Bohemian Rhapsody (Official Video)", "Queen", "Bohemian Rhapsody"},
+		{"topic channel", "Queen - Topic", "Queen - Bohemian Rhapsody", "Queen", "Bohemian Rhapsody"},
+		{"vevo channel", "QueenVEVO", "Queen - Bohemian Rhapsody", "Queen", "Bohemian Rhapsody"},
+		{"live suffix kept", "Queen", "Bohemian Rhapsody - Live Aid", "Queen", "Bohemian Rhapsody - Live Aid"},
+		{"featured artist", "Daft Punk feat. Pharrell Williams", "Get Lucky [feat. Pharrell Williams]", "Daft Punk", "Get Lucky"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artist, title, ok := CleanTags(tt.artist, tt.title)
+			if !ok || artist != tt.wantArtist || title != tt.wantTitle {
+				t.Errorf("CleanTags(%q, %q) = %q, %q, %v; want %q, %q", tt.artist, tt.title, artist, title, ok, tt.wantArtist, tt.wantTitle)
+			}
+		})
+	}
+}
@@ -2,125 +2,185 @@ package parse
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 )
 
+// ollamaKeepAlive is how long Ollama keeps a warmed model loaded.
+const ollamaKeepAlive = "30m"
+
 type OllamaProvider struct {
-	model string
+	engine
+	model  string
+	host   string
+	client *http.Client
 }
 
-func NewOllamaProvider(model string) *OllamaProvider {
+func NewOllamaProvider(model, host string, client *http.Client) *OllamaProvider {
 	if model == "" {
 		model = "qwen2.5-coder:14b"
 	}
-	return &OllamaProvider{model: model}
+	p := &OllamaProvider{model: model, host: host, client: client}
+	p.engine = newEngine(p)
+	return p
 }
 
-func (p *OllamaProvider) Name() string         { return "Ollama" }
-func (p *OllamaProvider) ID() ProviderID       { return ProviderOllama }
+func (p *OllamaProvider) Name() string          { return "Ollama" }
+func (p *OllamaProvider) ID() ProviderID        { return ProviderOllama }
 func (p *OllamaProvider) RequiresAPIKey() bool  { return false }
 func (p *OllamaProvider) DefaultEnvVar() string { return "" }
 func (p *OllamaProvider) DefaultModel() string  { return "qwen2.5-coder:14b" }
+func (p *OllamaProvider) Model() string         { return p.model }
+
+func (p *OllamaProvider) generate(ctx context.Context, r request) (string, Usage, error) {
+	resp, err := p.post(ctx, r.promptWithExample(), false)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
 
-func (p *OllamaProvider) Parse(query string) (string, string, error) {
-	prompt := fmt.Sprintf("Parse this song query and extract the artist and title. If any information is missing or misspelled, use your knowledge to complete it correctly. Respond with JSON only: {\"artist\": \"...\", \"title\": \"...\"}. Query: %s", query)
+	var result ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", Usage{}, unanswered(fmt.Errorf("failed to parse ollama response: %w", err))
+	}
+	return result.Response, result.usage(), nil
+}
+
+// stream is generate with the answer streamed as newline-delimited JSON.
+// The last line carries the usage.
+func (p *OllamaProvider) stream(ctx context.Context, r request, onText func(string)) (Usage, error) {
+	resp, err := p.post(ctx, r.promptWithExample(), true)
+	if err != nil {
+		return Usage{}, err
+	}
+	defer resp.Body.Close()
 
-	body := map[string]interface{}{
+	var usage Usage
+	err = readLines(resp.Body, func(line []byte) error {
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return unanswered(fmt.Errorf("failed to parse ollama stream: %w", err))
+		}
+		if chunk.Error != "" {
+			return unanswered(fmt.Errorf("ollama: %s", chunk.Error))
+		}
+		onText(chunk.Response)
+		if chunk.Done {
+			usage = chunk.usage()
+			return io.EOF
+		}
+		return nil
+	})
+	return usage, err
+}
+
+type ollamaResponse struct {
+	Response        string `json:"response"`
+	Done            bool   `json:"done"`
+	Error           string `json:"error"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+}
+
+func (r ollamaResponse) usage() Usage {
+	return Usage{InputTokens: r.PromptEvalCount, OutputTokens: r.EvalCount}
+}
+
+// post sends prompt to /api/generate asking for JSON and returns the
+// response once it has a 200 status.
+func (p *OllamaProvider) post(ctx context.Context, prompt string, stream bool) (*http.Response, error) {
+	jsonBody, err := json.Marshal(map[string]interface{}{
 		"model":  p.model,
 		"prompt": prompt,
 		"format": "json",
-		"stream": false,
+		"stream": stream,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	jsonBody, err := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, "POST", p.host+"/api/generate", bytes.NewReader(jsonBody))
 	if err != nil {
-		return "", "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	req, err := http.NewRequest("POST", "http://localhost:11434/api/generate", bytes.NewReader(jsonBody))
+	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create request: %w", err)
+		return nil, unanswered(fmt.Errorf("ollama request failed (is ollama running?): %w", err))
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, unanswered(fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(respBody)))
+	}
+	return resp, nil
+}
+
+// ListModels returns the models pulled into the local Ollama.
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.host+"/api/tags", nil)
 	if err != nil {
-		return "", "", fmt.Errorf("ollama request failed (is ollama running?): %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("ollama request failed (is ollama running?): %w", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return "", "", fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("ollama returned status %d", resp.StatusCode)
 	}
 
 	var result struct {
-		Response string `json:"response"`
+		Models []struct {
+			Name string `json:"name"`
+			Size int64  `json:"size"`
+		} `json:"models"`
 	}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", "", fmt.Errorf("failed to parse ollama response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse ollama models: %w", err)
 	}
 
-	var song parsedSong
-	if err := json.Unmarshal([]byte(result.Response), &song); err != nil {
-		return "", "", fmt.Errorf("failed to parse song json: %w", err)
+	models := make([]ModelInfo, 0, len(result.Models))
+	for _, m := range result.Models {
+		models = append(models, ModelInfo{Name: m.Name, Size: m.Size})
 	}
-
-	return song.Artist, song.Title, nil
+	return models, nil
 }
 
-func (p *OllamaProvider) FetchLyrics(query string) (string, string, string, error) {
-	body := map[string]interface{}{
-		"model":  p.model,
-		"prompt": fmt.Sprintf("Identify the song and give me the full lyrics for: %s\nKeep empty lines between verses/chorus sections. Respond with JSON only: {\"artist\": \"...\", \"song\": \"...\", \"lyrics\": \"...\"}", query),
-		"format": "json",
-		"stream": false,
-	}
-
-	jsonBody, err := json.Marshal(body)
+// Warm loads the model into memory and keeps it there for a while, so the
+// next query doesn't wait for a cold load.
+func (p *OllamaProvider) Warm(ctx context.Context) error {
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"model":      p.model,
+		"keep_alive": ollamaKeepAlive,
+	})
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to marshal request: %w", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "http://localhost:11434/api/generate", bytes.NewReader(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.host+"/api/generate", bytes.NewReader(jsonBody))
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", "", "", fmt.Errorf("ollama request failed (is ollama running?): %w", err)
+		return fmt.Errorf("ollama request failed (is ollama running?): %w", err)
 	}
 	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", "", "", fmt.Errorf("failed to read response: %w", err)
-	}
+	io.Copy(io.Discard, resp.Body)
 
 	if resp.StatusCode != 200 {
-		return "", "", "", fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(respBody))
-	}
-
-	var result struct {
-		Response string `json:"response"`
-	}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", "", "", fmt.Errorf("failed to parse ollama response: %w", err)
+		return fmt.Errorf("ollama returned status %d", resp.StatusCode)
 	}
-
-	var lr lyricsResult
-	if err := json.Unmarshal([]byte(result.Response), &lr); err != nil {
-		return "", "", "", fmt.Errorf("failed to parse lyrics json: %w", err)
-	}
-
-	return lr.Artist, lr.Song, lr.Lyrics, nil
+	return nil
 }
@@ -0,0 +1,124 @@
+package parse
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unreachableHost returns the address of a server that is no longer
+// listening.
+func unreachableHost(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func TestOllamaListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/tags" {
+			t.Errorf("request = %s %s, want GET /api/tags", r.Method, r.URL.Path)
+		}
+		io.WriteString(w, `{"models": [
+			{"name": "qwen2.5-coder:14b", "model": "qwen2.5-coder:14b", "size": 8988124069, "details": {"family": "qwen2"}},
+			{"name": "llama3.2:latest", "size": 2019393189}
+		]}`)
+	}))
+	defer srv.Close()
+
+	models, err := NewOllamaProvider("", srv.URL, srv.Client()).ListModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ModelInfo{{Name: "qwen2.5-coder:14b", Size: 8988124069}, {Name: "llama3.2:latest", Size: 2019393189}}
+	if !reflect.DeepEqual(models, want) {
+		t.Errorf("ListModels() = %+v, want %+v", models, want)
+	}
+}
+
+func TestOllamaListModelsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    func(t *testing.T) string
+		wantErr string
+	}{
+		{"unreachable", unreachableHost, "is ollama running?"},
+		{"error status", statusHost(http.StatusInternalServerError, ""), "status 500"},
+		{"not json", statusHost(http.StatusOK, "<html>"), "failed to parse ollama models"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models, err := NewOllamaProvider("", tt.host(t), http.DefaultClient).ListModels(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ListModels() = %+v, %v; want an error containing %q", models, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// statusHost starts a server answering every request with status and body.
+func statusHost(status int, body string) func(t *testing.T) string {
+	return func(t *testing.T) string {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			io.WriteString(w, body)
+		}))
+		t.Cleanup(srv.Close)
+		return srv.URL
+	}
+}
+
+func TestOllamaWarm(t *testing.T) {
+	var warmed map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/generate" {
+			t.Errorf("request = %s %s, want POST /api/generate", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&warmed); err != nil {
+			t.Errorf("request is not json: %v", err)
+		}
+		io.WriteString(w, `{"model": "llama3.2", "response": "", "done": true, "done_reason": "load"}`)
+	}))
+	defer srv.Close()
+
+	if err := NewOllamaProvider("llama3.2", srv.URL, srv.Client()).Warm(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// no prompt: Ollama only loads the model
+	want := map[string]interface{}{"model": "llama3.2", "keep_alive": ollamaKeepAlive}
+	if !reflect.DeepEqual(warmed, want) {
+		t.Errorf("warm request = %v, want %v", warmed, want)
+	}
+}
+
+func TestOllamaWarmErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    func(t *testing.T) string
+		wantErr string
+	}{
+		{"unreachable", unreachableHost, "is ollama running?"},
+		{"model not pulled", statusHost(http.StatusNotFound, `{"error": "model not found"}`), "status 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			start := time.Now()
+			err := NewOllamaProvider("", tt.host(t), http.DefaultClient).Warm(ctx)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Warm() error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if time.Since(start) > time.Second {
+				t.Errorf("Warm() took %v to give up", time.Since(start))
+			}
+		})
+	}
+}
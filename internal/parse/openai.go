@@ -2,114 +2,162 @@ package parse
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+
+	"lyrics-tui/internal/httpclient"
+)
+
+// Output modes for OpenAI-style chat APIs. Many local servers reject
+// json_schema; prompt mode works with any model.
+const (
+	OutputJSONSchema = "json_schema"
+	OutputJSONObject = "json_object"
+	OutputPrompt     = "prompt"
 )
 
+// OpenAIProvider talks to the OpenAI chat completions API, or to any server
+// speaking it when created as the openai-compatible provider.
 type OpenAIProvider struct {
-	apiKey string
-	model  string
+	engine
+	id      ProviderID
+	apiKey  string
+	model   string
+	baseURL string
+	mode    string
+	client  *http.Client
 }
 
-func NewOpenAIProvider(apiKey, model string) *OpenAIProvider {
+func NewOpenAIProvider(apiKey, model, baseURL string, client *http.Client) *OpenAIProvider {
 	if model == "" {
 		model = "gpt-5.2"
 	}
-	return &OpenAIProvider{apiKey: apiKey, model: model}
+	p := &OpenAIProvider{id: ProviderOpenAI, apiKey: apiKey, model: model, baseURL: baseURL, mode: OutputJSONSchema, client: client}
+	p.engine = newEngine(p)
+	return p
 }
 
-func (p *OpenAIProvider) Name() string         { return "OpenAI" }
-func (p *OpenAIProvider) ID() ProviderID       { return ProviderOpenAI }
-func (p *OpenAIProvider) RequiresAPIKey() bool  { return true }
-func (p *OpenAIProvider) DefaultEnvVar() string { return "OPENAI_API_KEY" }
-func (p *OpenAIProvider) DefaultModel() string  { return "gpt-5.2" }
-
-func (p *OpenAIProvider) Parse(query string) (string, string, error) {
-	if p.apiKey == "" {
-		return "", "", fmt.Errorf("OpenAI API key not set (set OPENAI_API_KEY or configure in settings with Ctrl+O)")
-	}
-
-	body := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]string{
-			{
-				"role":    "user",
-				"content": fmt.Sprintf("Parse this song query and extract the artist and title. If any information is missing or misspelled, use your knowledge to complete it correctly. Query: %s", query),
-			},
-		},
-		"response_format": map[string]interface{}{
-			"type": "json_schema",
-			"json_schema": map[string]interface{}{
-				"name":   "song",
-				"strict": true,
-				"schema": map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"artist": map[string]string{"type": "string"},
-						"title":  map[string]string{"type": "string"},
-					},
-					"required":             []string{"artist", "title"},
-					"additionalProperties": false,
-				},
-			},
-		},
-	}
+// NewOpenAICompatibleProvider creates a provider for vLLM, llama.cpp, LM
+// Studio and other servers with an OpenAI-style API at baseURL. The API key
+// is optional and mode selects how JSON answers are requested, defaulting
+// to json_object.
+func NewOpenAICompatibleProvider(apiKey, model, baseURL, mode string, client *http.Client) *OpenAIProvider {
+	switch mode {
+	case OutputJSONSchema, OutputJSONObject, OutputPrompt:
+	default:
+		mode = OutputJSONObject
+	}
+	p := &OpenAIProvider{id: ProviderOpenAICompatible, apiKey: apiKey, model: model, baseURL: baseURL, mode: mode, client: client}
+	p.engine = newEngine(p)
+	return p
+}
 
-	jsonBody, err := json.Marshal(body)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to marshal request: %w", err)
-	}
+func (p *OpenAIProvider) Name() string          { return ProviderName(p.id) }
+func (p *OpenAIProvider) ID() ProviderID        { return p.id }
+func (p *OpenAIProvider) RequiresAPIKey() bool  { return p.id == ProviderOpenAI }
+func (p *OpenAIProvider) DefaultEnvVar() string { return "OPENAI_API_KEY" }
+func (p *OpenAIProvider) DefaultModel() string  { return DefaultModelForProvider(p.id) }
+func (p *OpenAIProvider) Model() string         { return p.model }
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(jsonBody))
+func (p *OpenAIProvider) generate(ctx context.Context, r request) (string, Usage, error) {
+	body, err := p.chatBody(r)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create request: %w", err)
+		return "", Usage{}, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+p.apiKey)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.post(ctx, body)
 	if err != nil {
-		return "", "", fmt.Errorf("openai request failed: %w", err)
+		return "", Usage{}, err
 	}
 	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != 200 {
-		return "", "", fmt.Errorf("openai returned status %d: %s", resp.StatusCode, string(respBody))
-	}
-
 	var result struct {
 		Choices []struct {
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage *openAIUsage `json:"usage"`
 	}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", "", fmt.Errorf("failed to parse openai response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", Usage{}, unanswered(fmt.Errorf("failed to parse %s response: %w", p.id, err))
 	}
 
 	if len(result.Choices) == 0 {
-		return "", "", fmt.Errorf("openai returned no choices")
+		return "", Usage{}, unanswered(fmt.Errorf("%s returned no choices", p.id))
 	}
+	return result.Choices[0].Message.Content, result.Usage.usage(), nil
+}
 
-	var song parsedSong
-	if err := json.Unmarshal([]byte(result.Choices[0].Message.Content), &song); err != nil {
-		return "", "", fmt.Errorf("failed to parse song json: %w", err)
+// stream is generate with the answer streamed as server-sent events.
+func (p *OpenAIProvider) stream(ctx context.Context, r request, onText func(string)) (Usage, error) {
+	body, err := p.chatBody(r)
+	if err != nil {
+		return Usage{}, err
+	}
+	body["stream"] = true
+	if p.id == ProviderOpenAI {
+		// only OpenAI is sure to accept this; compatible servers that
+		// report usage do so unasked
+		body["stream_options"] = map[string]bool{"include_usage": true}
 	}
 
-	return song.Artist, song.Title, nil
+	resp, err := p.post(ctx, body)
+	if err != nil {
+		return Usage{}, err
+	}
+	defer resp.Body.Close()
+
+	var usage Usage
+	err = readSSE(resp.Body, func(data []byte) error {
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *openAIUsage `json:"usage"`
+		}
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return unanswered(fmt.Errorf("failed to parse %s stream: %w", p.id, err))
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage.usage()
+		}
+		if len(chunk.Choices) > 0 {
+			onText(chunk.Choices[0].Delta.Content)
+		}
+		return nil
+	})
+	return usage, err
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u *openAIUsage) usage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens}
 }
 
-func (p *OpenAIProvider) FetchLyrics(query string) (string, string, string, error) {
-	if p.apiKey == "" {
-		return "", "", "", fmt.Errorf("OpenAI API key not set (set OPENAI_API_KEY or configure in settings with Ctrl+O)")
+// chatBody builds a chat completion request asking for JSON in the
+// provider's output mode. Outside json_schema mode the prompt asks for the
+// JSON shape itself.
+func (p *OpenAIProvider) chatBody(r request) (map[string]interface{}, error) {
+	if p.apiKey == "" && p.RequiresAPIKey() {
+		return nil, fmt.Errorf("OpenAI API key not set (set OPENAI_API_KEY or configure in settings with Ctrl+O)")
+	}
+
+	prompt := r.prompt
+	if p.mode != OutputJSONSchema {
+		prompt = r.promptWithExample()
 	}
 
 	body := map[string]interface{}{
@@ -117,74 +165,88 @@ func (p *OpenAIProvider) FetchLyrics(query string) (string, string, string, erro
 		"messages": []map[string]string{
 			{
 				"role":    "user",
-				"content": fmt.Sprintf("Identify the song and give me the full lyrics for: %s\nKeep empty lines between verses/chorus sections.", query),
+				"content": prompt,
 			},
 		},
-		"response_format": map[string]interface{}{
+	}
+	switch p.mode {
+	case OutputJSONSchema:
+		body["response_format"] = map[string]interface{}{
 			"type": "json_schema",
 			"json_schema": map[string]interface{}{
-				"name":   "lyrics",
+				"name":   r.name,
 				"strict": true,
-				"schema": map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"artist": map[string]string{"type": "string"},
-						"song":   map[string]string{"type": "string"},
-						"lyrics": map[string]string{"type": "string"},
-					},
-					"required":             []string{"artist", "song", "lyrics"},
-					"additionalProperties": false,
-				},
+				"schema": r.schema(),
 			},
-		},
+		}
+	case OutputJSONObject:
+		body["response_format"] = map[string]string{"type": "json_object"}
 	}
+	return body, nil
+}
 
+// post sends a chat completion request and returns the response once it
+// has a 200 status.
+func (p *OpenAIProvider) post(ctx context.Context, body map[string]interface{}) (*http.Response, error) {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(jsonBody))
+	req, err := http.NewRequestWithContext(httpclient.AllowRetry(ctx), "POST", p.baseURL+"/chat/completions", bytes.NewReader(jsonBody))
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+p.apiKey)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", "", "", fmt.Errorf("openai request failed: %w", err)
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to read response: %w", err)
+		return nil, unanswered(fmt.Errorf("%s request failed: %w", p.id, err))
 	}
 
 	if resp.StatusCode != 200 {
-		return "", "", "", fmt.Errorf("openai returned status %d: %s", resp.StatusCode, string(respBody))
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, unanswered(fmt.Errorf("%s returned status %d: %s", p.id, resp.StatusCode, string(respBody)))
 	}
+	return resp, nil
+}
 
-	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+// ListModels returns the models the server offers at /models.
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", "", "", fmt.Errorf("failed to parse openai response: %w", err)
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	}
 
-	if len(result.Choices) == 0 {
-		return "", "", "", fmt.Errorf("openai returned no choices")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", p.id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s returned status %d", p.id, resp.StatusCode)
 	}
 
-	var lr lyricsResult
-	if err := json.Unmarshal([]byte(result.Choices[0].Message.Content), &lr); err != nil {
-		return "", "", "", fmt.Errorf("failed to parse lyrics json: %w", err)
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse %s models: %w", p.id, err)
 	}
 
-	return lr.Artist, lr.Song, lr.Lyrics, nil
+	models := make([]ModelInfo, 0, len(result.Data))
+	for _, m := range result.Data {
+		models = append(models, ModelInfo{Name: m.ID})
+	}
+	return models, nil
 }
@@ -0,0 +1,166 @@
+package parse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// openAIServer serves /chat/completions with handle, which gets the decoded
+// request body, and /models with a fixed list.
+func openAIServer(t *testing.T, handle func(w http.ResponseWriter, r *http.Request, req map[string]interface{})) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/models" {
+			io.WriteString(w, `{"object": "list", "data": [{"id": "llama-3.1-8b"}, {"id": "qwen2.5-7b"}]}`)
+			return
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/chat/completions" {
+			t.Errorf("request = %s %s, want POST /chat/completions", r.Method, r.URL.Path)
+		}
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("request is not json: %v", err)
+		}
+		handle(w, r, req)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func answerSong(w http.ResponseWriter) {
+	io.WriteString(w, `{
+		"choices": [{"message": {"role": "assistant", "content": "{\"artist\": \"Queen\", \"title\": \"Bohemian Rhapsody\"}"}}],
+		"usage": {"prompt_tokens": 40, "completion_tokens": 12}
+	}`)
+}
+
+func TestOpenAICompatibleOutputModes(t *testing.T) {
+	tests := []struct {
+		mode       string
+		wantFormat string // response_format type, empty for none
+	}{
+		{OutputJSONSchema, "json_schema"},
+		{OutputJSONObject, "json_object"},
+		{OutputPrompt, ""},
+		{"", "json_object"},
+		{"bogus", "json_object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			srv, _ := openAIServer(t, func(w http.ResponseWriter, r *http.Request, req map[string]interface{}) {
+				if auth := r.Header.Get("Authorization"); auth != "" {
+					t.Errorf("Authorization = %q without an API key", auth)
+				}
+				format, _ := req["response_format"].(map[string]interface{})
+				if got, _ := format["type"].(string); got != tt.wantFormat {
+					t.Errorf("response_format = %v, want type %q", req["response_format"], tt.wantFormat)
+				}
+				if tt.wantFormat == "json_schema" {
+					schema, _ := format["json_schema"].(map[string]interface{})
+					if schema["name"] != "song" || schema["strict"] != true || schema["schema"] == nil {
+						t.Errorf("json_schema = %v", format["json_schema"])
+					}
+				}
+
+				messages, _ := req["messages"].([]interface{})
+				prompt := messages[0].(map[string]interface{})["content"].(string)
+				if asksForJSON := strings.Contains(prompt, "Respond with JSON only"); asksForJSON != (tt.wantFormat != "json_schema") {
+					t.Errorf("prompt %q: asks for the JSON shape = %v", prompt, asksForJSON)
+				}
+				answerSong(w)
+			})
+
+			p := NewOpenAICompatibleProvider("", "local-model", srv.URL, tt.mode, srv.Client())
+			artist, title, err := p.Parse(context.Background(), "queen bohemian")
+			if err != nil || artist != "Queen" || title != "Bohemian Rhapsody" {
+				t.Errorf("Parse() = %q, %q, %v", artist, title, err)
+			}
+		})
+	}
+}
+
+func TestOpenAICompatibleRejectedResponseFormat(t *testing.T) {
+	// like many local servers, reject what they don't support
+	srv, _ := openAIServer(t, func(w http.ResponseWriter, r *http.Request, req map[string]interface{}) {
+		if _, ok := req["response_format"]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error": {"message": "response_format is not supported"}}`)
+			return
+		}
+		answerSong(w)
+	})
+
+	p := NewOpenAICompatibleProvider("", "local-model", srv.URL, OutputJSONSchema, srv.Client())
+	_, _, err := p.Parse(context.Background(), "queen bohemian")
+	if err == nil || !strings.Contains(err.Error(), "status 400") || !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("Parse() error = %v, want the server's 400", err)
+	}
+	var ue *unansweredError
+	if !errors.As(err, &ue) {
+		t.Error("rejected request does not let a fallback take over")
+	}
+
+	p = NewOpenAICompatibleProvider("", "local-model", srv.URL, OutputPrompt, srv.Client())
+	if _, title, err := p.Parse(context.Background(), "queen bohemian"); err != nil || title != "Bohemian Rhapsody" {
+		t.Errorf("Parse() in prompt mode = %q, %v", title, err)
+	}
+}
+
+func TestOpenAIAPIKey(t *testing.T) {
+	srv, calls := openAIServer(t, func(w http.ResponseWriter, r *http.Request, req map[string]interface{}) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer key" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		format, _ := req["response_format"].(map[string]interface{})
+		if format["type"] != "json_schema" {
+			t.Errorf("OpenAI asked with response_format %v, want json_schema", req["response_format"])
+		}
+		answerSong(w)
+	})
+
+	if _, _, err := NewOpenAIProvider("key", "", srv.URL, srv.Client()).Parse(context.Background(), "query"); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(calls, 0)
+	if _, _, err := NewOpenAIProvider("", "", srv.URL, srv.Client()).Parse(context.Background(), "query"); err == nil || !strings.Contains(err.Error(), "API key not set") {
+		t.Errorf("Parse() error = %v, want a missing key error", err)
+	}
+	if atomic.LoadInt32(calls) != 0 {
+		t.Error("request sent without an API key")
+	}
+}
+
+func TestOpenAIListModels(t *testing.T) {
+	srv, _ := openAIServer(t, nil)
+	p := NewOpenAICompatibleProvider("", "", srv.URL, "", srv.Client())
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []ModelInfo{{Name: "llama-3.1-8b"}, {Name: "qwen2.5-7b"}}; !reflect.DeepEqual(models, want) {
+		t.Errorf("ListModels() = %+v, want %+v", models, want)
+	}
+
+	for name, handler := range map[string]http.HandlerFunc{
+		"error status": func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+		"not json":     func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "<html>") },
+	} {
+		srv := httptest.NewServer(handler)
+		p := NewOpenAICompatibleProvider("", "", srv.URL, "", srv.Client())
+		if models, err := p.ListModels(context.Background()); err == nil {
+			t.Errorf("%s: ListModels() = %+v, want error", name, models)
+		}
+		srv.Close()
+	}
+}
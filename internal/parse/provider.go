@@ -1,8 +1,11 @@
 package parse
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
 
 	"lyrics-tui/internal/config"
 )
@@ -10,23 +13,62 @@ import (
 type ProviderID string
 
 const (
-	ProviderOpenAI ProviderID = "openai"
-	ProviderGemini ProviderID = "gemini"
-	ProviderOllama ProviderID = "ollama"
+	ProviderOpenAI           ProviderID = "openai"
+	ProviderGemini           ProviderID = "gemini"
+	ProviderOllama           ProviderID = "ollama"
+	ProviderAnthropic        ProviderID = "anthropic"
+	ProviderOpenAICompatible ProviderID = "openai-compatible"
+	ProviderHeuristic        ProviderID = "heuristic"
 )
 
-var AllProviders = []ProviderID{ProviderOpenAI, ProviderGemini, ProviderOllama}
+var AllProviders = []ProviderID{ProviderOpenAI, ProviderGemini, ProviderOllama, ProviderAnthropic, ProviderOpenAICompatible, ProviderHeuristic}
 
 type Provider interface {
 	Name() string
 	ID() ProviderID
-	Parse(query string) (artist, title string, err error)
-	FetchLyrics(query string) (artist, title, lyrics string, err error)
+	Parse(ctx context.Context, query string) (artist, title string, err error)
+	FetchLyrics(ctx context.Context, query string) (artist, title, lyrics string, err error)
 	RequiresAPIKey() bool
 	DefaultEnvVar() string
 	DefaultModel() string
 }
 
+// Candidate is a song a provider thinks a lyric fragment comes from.
+type Candidate struct {
+	Artist string
+	Title  string
+}
+
+// Identifier is implemented by providers that can name the songs a lyric
+// fragment may come from, most likely first.
+type Identifier interface {
+	Identify(ctx context.Context, fragment string) ([]Candidate, error)
+}
+
+// Translator is implemented by providers that can translate lyrics line by
+// line, returning one translated line per line given.
+type Translator interface {
+	Translate(ctx context.Context, lines []string, language string) ([]string, error)
+}
+
+// ModelInfo describes a model a provider can serve. Size is in bytes, 0
+// when unknown.
+type ModelInfo struct {
+	Name string
+	Size int64
+}
+
+// ModelLister is implemented by providers that can list their models.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// Warmer is implemented by local providers that benefit from loading their
+// model ahead of the first query.
+type Warmer interface {
+	Warm(ctx context.Context) error
+}
+
 func ProviderName(id ProviderID) string {
 	switch id {
 	case ProviderOpenAI:
@@ -35,6 +77,12 @@ func ProviderName(id ProviderID) string {
 		return "Gemini"
 	case ProviderOllama:
 		return "Ollama"
+	case ProviderAnthropic:
+		return "Anthropic"
+	case ProviderOpenAICompatible:
+		return "OpenAI-compatible"
+	case ProviderHeuristic:
+		return "Heuristic"
 	default:
 		return string(id)
 	}
@@ -48,12 +96,44 @@ func DefaultModelForProvider(id ProviderID) string {
 		return "gemini-3-pro-preview"
 	case ProviderOllama:
 		return "qwen2.5-coder:14b"
+	case ProviderAnthropic:
+		return "claude-sonnet-4-5"
 	default:
 		return ""
 	}
 }
 
-func NewProviderFromConfig(cfg *config.Config) (Provider, error) {
+// NewProviderFromConfig creates the configured provider, asking with the
+// configured prompts and recording usage in ledger. With AI fallbacks
+// configured, it is a FallbackProvider trying them after it.
+func NewProviderFromConfig(cfg *config.Config, client *http.Client, ledger *Ledger) (Provider, error) {
+	provider, err := newProvider(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.AIFallbacks) > 0 {
+		providers := []Provider{provider}
+		for _, entry := range cfg.AIFallbacks {
+			fallback, err := newFallback(cfg, entry, client)
+			if err != nil {
+				return nil, err
+			}
+			if fallback.ID() != provider.ID() {
+				providers = append(providers, fallback)
+			}
+		}
+		provider = NewFallbackProvider(providers...)
+	}
+	if e, ok := provider.(interface{ setPrompts(Prompts) }); ok {
+		e.setPrompts(PromptsFromConfig(cfg))
+	}
+	if e, ok := provider.(interface{ setLedger(*Ledger) }); ok && ledger != nil {
+		e.setLedger(ledger)
+	}
+	return provider, nil
+}
+
+func newProvider(cfg *config.Config, client *http.Client) (Provider, error) {
 	id := ProviderID(cfg.Provider)
 	model := cfg.Model
 	if model == "" {
@@ -66,16 +146,48 @@ func NewProviderFromConfig(cfg *config.Config) (Provider, error) {
 		if key == "" {
 			key = os.Getenv("OPENAI_API_KEY")
 		}
-		return NewOpenAIProvider(key, model), nil
+		return NewOpenAIProvider(key, model, cfg.Endpoint(config.EndpointOpenAI), client), nil
 	case ProviderGemini:
 		key := cfg.APIKey
 		if key == "" {
 			key = os.Getenv("GEMINI_API_KEY")
 		}
-		return NewGeminiProvider(key, model), nil
+		return NewGeminiProvider(key, model, cfg.Endpoint(config.EndpointGemini), client), nil
 	case ProviderOllama:
-		return NewOllamaProvider(model), nil
+		return NewOllamaProvider(model, cfg.Endpoint(config.EndpointOllama), client), nil
+	case ProviderAnthropic:
+		key := cfg.APIKey
+		if key == "" {
+			key = os.Getenv("ANTHROPIC_API_KEY")
+		}
+		return NewAnthropicProvider(key, model, cfg.Endpoint(config.EndpointAnthropic), client), nil
+	case ProviderOpenAICompatible:
+		key := cfg.APIKey
+		if key == "" {
+			key = os.Getenv("OPENAI_API_KEY")
+		}
+		return NewOpenAICompatibleProvider(key, model, cfg.Endpoint(config.EndpointOpenAICompatible), cfg.OutputMode, client), nil
+	case ProviderHeuristic:
+		return NewHeuristicProvider(), nil
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", cfg.Provider)
 	}
 }
+
+// newFallback creates the fallback provider for an "id" or "id:model"
+// entry. Its API key comes from the environment, as the configured one
+// belongs to the main provider.
+func newFallback(cfg *config.Config, entry string, client *http.Client) (Provider, error) {
+	fallback := *cfg
+	fallback.APIKey = ""
+	fallback.Model = ""
+	fallback.Provider = entry
+	if i := strings.Index(entry, ":"); i >= 0 {
+		fallback.Provider, fallback.Model = entry[:i], entry[i+1:]
+	}
+	provider, err := newProvider(&fallback, client)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ai fallback: %w", err)
+	}
+	return provider, nil
+}
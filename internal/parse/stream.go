@@ -0,0 +1,172 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// LyricsStreamer is implemented by providers that can stream lyrics while
+// the model is still writing them. onPartial is called with the answer so
+// far; artist and title usually arrive long before the lyrics are done.
+type LyricsStreamer interface {
+	StreamLyrics(ctx context.Context, query string, onPartial func(artist, title, lyrics string)) (artist, title, lyrics string, err error)
+}
+
+// lyricsStream accumulates a streamed lyrics JSON answer and reports each
+// change in its decoded fields.
+type lyricsStream struct {
+	text      strings.Builder
+	last      lyricsResult
+	onPartial func(artist, title, lyrics string)
+}
+
+func (s *lyricsStream) add(chunk string) {
+	if chunk == "" {
+		return
+	}
+	s.text.WriteString(chunk)
+	lr := partialLyrics(s.text.String())
+	if lr != s.last {
+		s.last = lr
+		s.onPartial(lr.Artist, lr.Song, lr.Lyrics)
+	}
+}
+
+// partialLyrics decodes as much of a lyrics JSON answer as has arrived,
+// including the string value still being written.
+func partialLyrics(text string) lyricsResult {
+	var lr lyricsResult
+	start := strings.Index(text, "{")
+	if start < 0 {
+		return lr
+	}
+
+	fields := map[string]*string{"artist": &lr.Artist, "song": &lr.Song, "lyrics": &lr.Lyrics}
+	key := ""
+	for i := start + 1; i < len(text); i++ {
+		if text[i] != '"' {
+			continue
+		}
+		s, n := partialString(text[i+1:])
+		i += n
+		if rest := strings.TrimLeft(text[i+1:], " \t\r\n"); strings.HasPrefix(rest, ":") {
+			key = s
+			continue
+		}
+		if f, ok := fields[key]; ok {
+			*f = s
+		}
+		key = ""
+	}
+	return lr
+}
+
+// partialString decodes a JSON string body up to its closing quote or the
+// end of s, returning the text and the number of bytes consumed. An escape
+// cut off at the end of s is left out.
+func partialString(s string) (string, int) {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '"' {
+			return sb.String(), i + 1
+		}
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+		if i+1 >= len(s) {
+			return sb.String(), len(s)
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'b', 'f':
+		case 'u':
+			r, n, ok := unicodeEscape(s[i+1:])
+			if !ok {
+				return sb.String(), len(s)
+			}
+			sb.WriteRune(r)
+			i += n
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String(), len(s)
+}
+
+// unicodeEscape decodes the hex digits of a \u escape, joining surrogate
+// pairs, and reports how many bytes it used.
+func unicodeEscape(s string) (rune, int, bool) {
+	if len(s) < 4 {
+		return 0, 0, false
+	}
+	v, err := strconv.ParseUint(s[:4], 16, 32)
+	if err != nil {
+		return unicode.ReplacementChar, 4, true
+	}
+	r := rune(v)
+	if !utf16.IsSurrogate(r) {
+		return r, 4, true
+	}
+	if len(s) < 10 {
+		return 0, 0, false
+	}
+	if s[4] == '\\' && s[5] == 'u' {
+		if low, err := strconv.ParseUint(s[6:10], 16, 32); err == nil {
+			return utf16.DecodeRune(r, rune(low)), 10, true
+		}
+	}
+	return unicode.ReplacementChar, 4, true
+}
+
+// readSSE calls fn with the data of each server-sent event in r until the
+// stream ends or sends [DONE].
+func readSSE(r io.Reader, fn func(data []byte) error) error {
+	return readLines(r, func(line []byte) error {
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			return nil
+		}
+		data := bytes.TrimSpace(line[len("data:"):])
+		if string(data) == "[DONE]" {
+			return io.EOF
+		}
+		return fn(data)
+	})
+}
+
+// readLines calls fn with each non-empty line of r, as in newline-delimited
+// JSON. fn returning io.EOF ends the stream early without error.
+func readLines(r io.Reader, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return unanswered(fmt.Errorf("failed to read stream: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPartialString(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		want  string
+		wantN int
+	}{
+		{"closed", `abc" , "next"`, "abc", 4},
+		{"missing terminator", `abc`, "abc", 3},
+		{"escapes", `a\nb\tc\"d\\e\/f"`, "a\nb\tc\"d\\e/f", 17},
+		{"cut off after backslash", `ab\`, "ab", 3},
+		{"cut off in unicode escape", `ab\u00`, "ab", 6},
+		{"unicode escape", `caf\u00e9"`, "café", 10},
+		{"surrogate pair", `\ud83c\udfb5"`, "🎵", 13},
+		{"split surrogate pair", `ab\ud83c\udf`, "ab", 12},
+		{"high surrogate at the end", `ab\ud83c`, "ab", 8},
+		{"lone surrogate", `\ud83cabcdef"`, "\ufffdabcdef", 13},
+		{"invalid hex", `\uzzzz!"`, "\ufffd!", 8},
+		{"raw utf-8", `été"`, "été", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n := partialString(tt.in)
+			if got != tt.want || n != tt.wantN {
+				t.Errorf("partialString(%q) = %q, %d; want %q, %d", tt.in, got, n, tt.want, tt.wantN)
+			}
+		})
+	}
+}
+
+func TestPartialLyrics(t *testing.T) {
+	tests := []struct {
+		in   string
+		want lyricsResult
+	}{
+		{``, lyricsResult{}},
+		{`Sure! {"art`, lyricsResult{}},
+		{`{"artist": "Que`, lyricsResult{Artist: "Que"}},
+		{`{"artist": "Queen", "song": "Bohemian Rhapsody", "lyrics": "Is this the real life?\nIs this`, lyricsResult{Artist: "Queen", Song: "Bohemian Rhapsody", Lyrics: "Is this the real life?\nIs this"}},
+		{`{"artist":"Queen","lyrics":"Mama, just killed a man\`, lyricsResult{Artist: "Queen", Lyrics: "Mama, just killed a man"}},
+		{`{"note": "song: x", "song" : "Title", "lyrics": "a \"quoted\" line"}`, lyricsResult{Song: "Title", Lyrics: `a "quoted" line`}},
+	}
+	for _, tt := range tests {
+		if got := partialLyrics(tt.in); got != tt.want {
+			t.Errorf("partialLyrics(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLyricsStreamReportsChanges(t *testing.T) {
+	var got []string
+	s := &lyricsStream{onPartial: func(artist, title, lyrics string) {
+		got = append(got, artist+"|"+title+"|"+lyrics)
+	}}
+	for _, chunk := range []string{`{"artist": "`, `Sigur R`, `ós", "song": "Hoppí`, `polla", "lyrics": "\ud83c`, `\udfb5 la\`, `n", "x": 1}`, ``} {
+		s.add(chunk)
+	}
+	want := []string{
+		"Sigur R||",
+		"Sigur Rós|Hoppí|",
+		"Sigur Rós|Hoppípolla|",
+		"Sigur Rós|Hoppípolla|🎵 la",
+		"Sigur Rós|Hoppípolla|🎵 la\n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("partials = %q, want %q", got, want)
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"done", "data: {\"a\":1}\n\ndata: {\"a\":2}\n\ndata: [DONE]\n\ndata: {\"a\":3}\n\n", []string{`{"a":1}`, `{"a":2}`}},
+		{"missing terminator", "data: {\"a\":1}\r\n\r\ndata:{\"a\":2}", []string{`{"a":1}`, `{"a":2}`}},
+		{"other fields", ": keep-alive\nevent: message\nid: 7\ndata: {\"a\":1}\nretry: 100\n\n", []string{`{"a":1}`}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := readSSE(strings.NewReader(tt.in), func(data []byte) error {
+				got = append(got, string(data))
+				return nil
+			})
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readSSE() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	var got []string
+	err := readLines(strings.NewReader("{\"n\":1}\n\n  {\"n\":2}  \n{\"done\":true}\n{\"n\":4}"), func(line []byte) error {
+		got = append(got, string(line))
+		if strings.Contains(string(line), `"done":true`) {
+			return io.EOF
+		}
+		return nil
+	})
+	if want := []string{`{"n":1}`, `{"n":2}`, `{"done":true}`}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("readLines() = %q, %v; want %q", got, err, want)
+	}
+
+	failure := errors.New("bad line")
+	if err := readLines(strings.NewReader("a\nb"), func([]byte) error { return failure }); err != failure {
+		t.Errorf("readLines() error = %v, want fn's error", err)
+	}
+
+	long := strings.Repeat("x", 2*1024*1024)
+	var ue *unansweredError
+	if err := readLines(strings.NewReader(long), func([]byte) error { return nil }); !errors.As(err, &ue) {
+		t.Errorf("readLines() of an oversized line = %v, want a read error", err)
+	}
+}
+
+// streamServer writes body in chunks, flushing after each.
+func streamServer(t *testing.T, chunks ...string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, chunk := range chunks {
+			io.WriteString(w, chunk)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// sse renders OpenAI chat completion deltas as server-sent events.
+func sse(deltas ...string) []string {
+	events := make([]string, len(deltas))
+	for i, d := range deltas {
+		events[i] = fmt.Sprintf("data: {\"choices\": [{\"delta\": {\"content\": %q}}]}\n\n", d)
+	}
+	return events
+}
+
+func TestStreamLyricsBackends(t *testing.T) {
+	answer := []string{`{"artist": "Queen", `, `"song": "Bohemian Rhapsody", `, `"lyrics": "Is this the real life?\nIs this just fantasy?"}`}
+	wantLyrics := "Is this the real life?\nIs this just fantasy?"
+
+	openAIChunks := append(sse(answer...), "data: {\"choices\": [], \"usage\": {\"prompt_tokens\": 10, \"completion_tokens\": 20}}\n\n", "data: [DONE]\n\n")
+	geminiChunks := make([]string, len(answer))
+	for i, part := range answer {
+		geminiChunks[i] = fmt.Sprintf("data: {\"candidates\": [{\"content\": {\"parts\": [{\"text\": %q}]}}], \"usageMetadata\": {\"promptTokenCount\": 10, \"candidatesTokenCount\": %d}}\r\n\r\n", part, 5*(i+1)+5)
+	}
+	ollamaChunks := make([]string, 0, len(answer)+2)
+	for _, part := range answer {
+		ollamaChunks = append(ollamaChunks, fmt.Sprintf("{\"response\": %q, \"done\": false}\n", part))
+	}
+	ollamaChunks = append(ollamaChunks, "{\"response\": \"\", \"done\": true, \"prompt_eval_count\": 10, \"eval_count\": 20}\n", "{\"response\": \"ignored after done\"}\n")
+
+	tests := []struct {
+		name     string
+		provider func(url string, client *http.Client) Provider
+		chunks   []string
+	}{
+		{"openai sse", func(url string, c *http.Client) Provider {
+			return NewOpenAICompatibleProvider("", "m", url, "", c)
+		}, openAIChunks},
+		{"gemini sse", func(url string, c *http.Client) Provider {
+			return NewGeminiProvider("key", "m", url, c)
+		}, geminiChunks},
+		{"ollama ndjson", func(url string, c *http.Client) Provider {
+			return NewOllamaProvider("m", url, c)
+		}, ollamaChunks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := streamServer(t, tt.chunks...)
+			p := tt.provider(srv.URL, srv.Client())
+			ledger := NewLedger(filepath.Join(t.TempDir(), "usage.json"), 0, 0)
+			p.(interface{ setLedger(*Ledger) }).setLedger(ledger)
+
+			var partials []string
+			artist, title, lyrics, err := p.(LyricsStreamer).StreamLyrics(context.Background(), "query", func(artist, title, lyrics string) {
+				partials = append(partials, lyrics)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if artist != "Queen" || title != "Bohemian Rhapsody" || lyrics != wantLyrics {
+				t.Errorf("StreamLyrics() = %q, %q, %q", artist, title, lyrics)
+			}
+			if len(partials) < 3 || partials[len(partials)-1] != wantLyrics {
+				t.Errorf("partial lyrics = %q, want them to grow into the answer", partials)
+			}
+			if total := ledger.Total(); total.Calls != 1 || total.Tokens() != 30 {
+				t.Errorf("recorded usage = %+v, want one call of 30 tokens", total)
+			}
+		})
+	}
+}
+
+func TestStreamLyricsTruncated(t *testing.T) {
+	// the stream ends without a terminator, in the middle of the answer
+	srv := streamServer(t, sse(`{"artist": "Queen", "song": "Bohemian Rhapsody", "lyrics": "Is this the real`)...)
+	p := NewOpenAICompatibleProvider("", "m", srv.URL, "", srv.Client())
+
+	_, _, _, err := p.StreamLyrics(context.Background(), "query", func(string, string, string) {})
+	var ue *unansweredError
+	if err == nil || !errors.As(err, &ue) {
+		t.Errorf("StreamLyrics() error = %v, want an unanswered decoding error", err)
+	}
+}
+
+func TestStreamLyricsOllamaError(t *testing.T) {
+	srv := streamServer(t, "{\"response\": \"{\\\"artist\\\"\", \"done\": false}\n", "{\"error\": \"model crashed\"}\n")
+	p := NewOllamaProvider("m", srv.URL, srv.Client())
+
+	_, _, _, err := p.StreamLyrics(context.Background(), "query", func(string, string, string) {})
+	if err == nil || !strings.Contains(err.Error(), "model crashed") {
+		t.Errorf("StreamLyrics() error = %v, want the error line", err)
+	}
+}
+
+func TestStreamLyricsCancelled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, sse(`{"artist": "Queen", "song": "Bohemian`)[0])
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := NewOpenAICompatibleProvider("", "m", srv.URL, "", srv.Client())
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, _, err := p.StreamLyrics(ctx, "query", func(artist, title, lyrics string) {
+			if artist == "Queen" {
+				cancel()
+			}
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("StreamLyrics() returned no error after being cancelled")
+		}
+		if ctx.Err() == nil {
+			t.Error("stream ended before it was cancelled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("StreamLyrics() kept reading after its context was cancelled")
+	}
+}
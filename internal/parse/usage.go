@@ -0,0 +1,152 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned instead of asking a model once the daily
+// AI cap is reached.
+var ErrBudgetExceeded = errors.New("daily AI budget reached")
+
+// ledgerDays is how many days of usage the ledger keeps.
+const ledgerDays = 90
+
+// Usage is what one answer cost in tokens, as reported by the provider.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+// DayUsage adds up a day's answers.
+type DayUsage struct {
+	Calls        int `json:"calls"`
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+}
+
+// Tokens is the day's input and output tokens together.
+func (d DayUsage) Tokens() int {
+	return d.InputTokens + d.OutputTokens
+}
+
+func (d DayUsage) add(other DayUsage) DayUsage {
+	return DayUsage{
+		Calls:        d.Calls + other.Calls,
+		InputTokens:  d.InputTokens + other.InputTokens,
+		OutputTokens: d.OutputTokens + other.OutputTokens,
+	}
+}
+
+// Ledger records AI usage per provider and day in a JSON file and enforces
+// the daily caps. A zero cap is no cap.
+type Ledger struct {
+	path     string
+	tokenCap int
+	callCap  int
+
+	mu   sync.Mutex
+	days map[string]map[ProviderID]DayUsage
+}
+
+// NewLedger opens the ledger stored at path, starting empty if it does not
+// exist yet. tokenCap and callCap limit the day's tokens and calls across
+// every provider.
+func NewLedger(path string, tokenCap, callCap int) *Ledger {
+	l := &Ledger{path: path, tokenCap: tokenCap, callCap: callCap}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &l.days)
+	}
+	if l.days == nil {
+		l.days = map[string]map[ProviderID]DayUsage{}
+	}
+	return l
+}
+
+func (l *Ledger) today() string {
+	return time.Now().Format("2006-01-02")
+}
+
+// Record adds one answer from provider id to today's usage.
+func (l *Ledger) Record(id ProviderID, u Usage) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := l.today()
+	if l.days[day] == nil {
+		l.days[day] = map[ProviderID]DayUsage{}
+	}
+	l.days[day][id] = l.days[day][id].add(DayUsage{Calls: 1, InputTokens: u.InputTokens, OutputTokens: u.OutputTokens})
+	return l.save()
+}
+
+// Today returns today's usage by provider.
+func (l *Ledger) Today() map[ProviderID]DayUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	today := map[ProviderID]DayUsage{}
+	for id, usage := range l.days[l.today()] {
+		today[id] = usage
+	}
+	return today
+}
+
+// Total returns today's usage across providers.
+func (l *Ledger) Total() DayUsage {
+	var total DayUsage
+	for _, usage := range l.Today() {
+		total = total.add(usage)
+	}
+	return total
+}
+
+// Caps returns the daily token and call caps, 0 when unlimited.
+func (l *Ledger) Caps() (tokens, calls int) {
+	return l.tokenCap, l.callCap
+}
+
+// Allow returns ErrBudgetExceeded once today's usage reached a cap.
+func (l *Ledger) Allow() error {
+	total := l.Total()
+	if l.tokenCap > 0 && total.Tokens() >= l.tokenCap {
+		return ErrBudgetExceeded
+	}
+	if l.callCap > 0 && total.Calls >= l.callCap {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+func (l *Ledger) save() error {
+	if len(l.days) > ledgerDays {
+		days := make([]string, 0, len(l.days))
+		for day := range l.days {
+			days = append(days, day)
+		}
+		sort.Strings(days)
+		for _, day := range days[:len(days)-ledgerDays] {
+			delete(l.days, day)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create usage dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(l.days, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %w", err)
+	}
+
+	if err := os.WriteFile(l.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write usage: %w", err)
+	}
+	return nil
+}
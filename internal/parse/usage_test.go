@@ -0,0 +1,159 @@
+package parse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLedgerCaps(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenCap  int
+		callCap   int
+		usage     []Usage
+		wantAllow []bool // after each answer
+	}{
+		{"no caps", 0, 0, []Usage{{1000, 1000}, {1000, 1000}}, []bool{true, true}},
+		{"token cap", 100, 0, []Usage{{30, 30}, {20, 19}, {0, 1}}, []bool{true, true, false}},
+		{"token cap overshot", 100, 0, []Usage{{10, 200}}, []bool{false}},
+		{"call cap", 0, 2, []Usage{{1, 1}, {1, 1}}, []bool{true, false}},
+		{"first cap reached", 1000, 3, []Usage{{1, 1}, {1, 1}, {1, 1}}, []bool{true, true, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLedger(filepath.Join(t.TempDir(), "usage.json"), tt.tokenCap, tt.callCap)
+			if err := l.Allow(); err != nil {
+				t.Fatalf("Allow() before any answer = %v", err)
+			}
+			for i, u := range tt.usage {
+				if err := l.Record(ProviderOllama, u); err != nil {
+					t.Fatal(err)
+				}
+				err := l.Allow()
+				if allowed := err == nil; allowed != tt.wantAllow[i] {
+					t.Errorf("Allow() after answer %d = %v, want allowed %v", i+1, err, tt.wantAllow[i])
+				}
+				if err != nil && !errors.Is(err, ErrBudgetExceeded) {
+					t.Errorf("Allow() = %v, want ErrBudgetExceeded", err)
+				}
+			}
+		})
+	}
+}
+
+func TestLedgerPersistsByProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage", "usage.json")
+	l := NewLedger(path, 50, 0)
+	for _, record := range []struct {
+		id ProviderID
+		u  Usage
+	}{
+		{ProviderOllama, Usage{10, 5}},
+		{ProviderAnthropic, Usage{3, 4}},
+		{ProviderOllama, Usage{1, 2}},
+	} {
+		if err := l.Record(record.id, record.u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened := NewLedger(path, 20, 0)
+	today := reopened.Today()
+	if want := (DayUsage{Calls: 2, InputTokens: 11, OutputTokens: 7}); today[ProviderOllama] != want {
+		t.Errorf("ollama usage = %+v, want %+v", today[ProviderOllama], want)
+	}
+	if want := (DayUsage{Calls: 1, InputTokens: 3, OutputTokens: 4}); today[ProviderAnthropic] != want {
+		t.Errorf("anthropic usage = %+v, want %+v", today[ProviderAnthropic], want)
+	}
+	if total := reopened.Total(); total.Calls != 3 || total.Tokens() != 25 {
+		t.Errorf("Total() = %+v, want 3 calls and 25 tokens", total)
+	}
+	// the caps come from the caller, not the file
+	if tokens, calls := reopened.Caps(); tokens != 20 || calls != 0 {
+		t.Errorf("Caps() = %d, %d", tokens, calls)
+	}
+	if err := reopened.Allow(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Allow() = %v, want the reopened usage to count", err)
+	}
+}
+
+func TestLedgerPrunesOldDays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	now := time.Now()
+	days := map[string]map[ProviderID]DayUsage{}
+	for i := 1; i <= ledgerDays+5; i++ {
+		day := now.AddDate(0, 0, -i).Format("2006-01-02")
+		days[day] = map[ProviderID]DayUsage{ProviderOllama: {Calls: i}}
+	}
+	data, err := json.Marshal(days)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLedger(path, 0, 0)
+	if total := l.Total(); total.Calls != 0 {
+		t.Errorf("Total() = %+v, want earlier days left out", total)
+	}
+	if err := l.Record(ProviderOllama, Usage{1, 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved map[string]map[ProviderID]DayUsage
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != ledgerDays {
+		t.Fatalf("saved %d days, want %d", len(saved), ledgerDays)
+	}
+	if _, ok := saved[now.Format("2006-01-02")]; !ok {
+		t.Error("today was pruned")
+	}
+	if _, ok := saved[now.AddDate(0, 0, -(ledgerDays-1)).Format("2006-01-02")]; !ok {
+		t.Error("the oldest day within the window was pruned")
+	}
+	if _, ok := saved[now.AddDate(0, 0, -ledgerDays).Format("2006-01-02")]; ok {
+		t.Error("a day past the window was kept")
+	}
+}
+
+// ollamaBackend answers as the ollama provider, so its usage is recorded.
+type ollamaBackend struct{ answerBackend }
+
+func (b *ollamaBackend) ID() ProviderID { return ProviderOllama }
+
+func TestEngineRecordError(t *testing.T) {
+	dir := t.TempDir()
+	// the ledger's directory is a file, so it can't be saved
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &ollamaBackend{answerBackend{answer: `{"artist": "Queen", "title": "Bohemian Rhapsody"}`}}
+	e := newEngine(b)
+	e.setLedger(NewLedger(filepath.Join(blocked, "usage.json"), 0, 0))
+	if _, _, err := e.Parse(context.Background(), "bohemian"); err == nil {
+		t.Error("Parse() succeeded without recording its usage")
+	}
+
+	e.setLedger(NewLedger(filepath.Join(dir, "usage.json"), 0, 0))
+	if _, _, err := e.Parse(context.Background(), "bohemian"); err != nil {
+		t.Errorf("Parse() = %v", err)
+	}
+	if calls := e.ledger.Total().Calls; calls != 1 {
+		t.Errorf("recorded %d calls, want 1", calls)
+	}
+}
@@ -15,7 +15,7 @@ func NewMPRISPlayer() *MPRISPlayer {
 }
 
 // CurrentSong retrieves the currently playing song via MPRIS.
-func (p *MPRISPlayer) CurrentSong() (string, string, error) {
+func (p *MPRISPlayer) CurrentSong() (Metadata, error) {
 	cmd := exec.Command("bash", "-c", `
 		player=$(busctl --user list | grep -oP 'org\.mpris\.MediaPlayer2\.\S+' | head -1)
 		if [ -z "$player" ]; then
@@ -26,29 +26,48 @@ func (p *MPRISPlayer) CurrentSong() (string, string, error) {
 
 		artist=$(echo "$metadata" | grep -oP 'xesam:artist.*?as \d+ "\K[^"]+' | head -1)
 		title=$(echo "$metadata" | grep -oP 'xesam:title.*?s "\K[^"]+' | head -1)
+		album=$(echo "$metadata" | grep -oP 'xesam:album" s "\K[^"]+' | head -1)
+		url=$(echo "$metadata" | grep -oP 'xesam:url" s "\K[^"]+' | head -1)
+		length=$(echo "$metadata" | grep -oP 'mpris:length.*?x \K\d+' | head -1)
 
 		echo "$artist"
 		echo "$title"
+		echo "$album"
+		echo "$url"
+		echo "$length"
 	`)
 
 	output, err := cmd.Output()
 	if err != nil {
-		return "", "", fmt.Errorf("no media player found")
+		return Metadata{}, fmt.Errorf("no media player found")
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
 	if len(lines) < 2 {
-		return "", "", fmt.Errorf("no media playing")
+		return Metadata{}, fmt.Errorf("no media playing")
 	}
 
-	artist := strings.TrimSpace(lines[0])
-	title := strings.TrimSpace(lines[1])
+	md := Metadata{
+		Artist: strings.TrimSpace(lines[0]),
+		Title:  strings.TrimSpace(lines[1]),
+	}
+	if len(lines) > 2 {
+		md.Album = strings.TrimSpace(lines[2])
+	}
+	if len(lines) > 3 {
+		md.URL = strings.TrimSpace(lines[3])
+	}
+	if len(lines) > 4 {
+		var lengthMicroseconds float64
+		fmt.Sscanf(lines[4], "%f", &lengthMicroseconds)
+		md.Length = lengthMicroseconds / 1000000.0
+	}
 
-	if artist == "" || title == "" {
-		return "", "", fmt.Errorf("incomplete metadata")
+	if md.Artist == "" || md.Title == "" {
+		return Metadata{}, fmt.Errorf("incomplete metadata")
 	}
 
-	return artist, title, nil
+	return md, nil
 }
 
 // Position retrieves the current playback position and duration.
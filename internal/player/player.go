@@ -1,9 +1,34 @@
 package player
 
+import (
+	"net/url"
+)
+
+// Metadata describes the currently playing track.
+type Metadata struct {
+	Artist string
+	Title  string
+	Album  string
+	// URL is the track location reported by the player, e.g. file:///music/song.flac.
+	URL string
+	// Length is the track duration in seconds, 0 if unknown.
+	Length float64
+}
+
+// FilePath returns the local file the track is played from, or "" when the
+// player is not playing a local file.
+func (md Metadata) FilePath() string {
+	u, err := url.Parse(md.URL)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return u.Path
+}
+
 // Player provides an interface to interact with media players.
 type Player interface {
-	// CurrentSong returns the currently playing song's artist and title.
-	CurrentSong() (artist, title string, err error)
+	// CurrentSong returns the currently playing song's metadata.
+	CurrentSong() (Metadata, error)
 
 	// Position returns the current playback position and total duration in seconds.
 	Position() (position, duration float64, err error)
@@ -1,10 +1,16 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"lyrics-tui/internal/lyrics"
+	"lyrics-tui/internal/parse"
+	"lyrics-tui/internal/player"
 )
 
 func tickEverySecond() tea.Cmd {
@@ -19,16 +25,25 @@ func tickPosition() tea.Cmd {
 	})
 }
 
+func tickQueue() tea.Cmd {
+	return tea.Tick(30*time.Second, func(t time.Time) tea.Msg {
+		return queueTickMsg(t)
+	})
+}
+
 func (m Model) detectCurrentSong() tea.Cmd {
 	return func() tea.Msg {
-		artist, title, err := m.player.CurrentSong()
+		md, err := m.player.CurrentSong()
 		if err != nil {
 			return mprisData{err: err}
 		}
 
 		return mprisData{
-			artist: artist,
-			title:  title,
+			artist: md.Artist,
+			title:  md.Title,
+			album:  md.Album,
+			url:    md.URL,
+			length: md.Length,
 		}
 	}
 }
@@ -48,17 +63,29 @@ func (m Model) getPlaybackPosition() tea.Cmd {
 }
 
 func (m Model) searchLyrics(query string) tea.Cmd {
-	if m.config.AILyrics {
-		return m.fetchAILyrics(query, "", "")
-	}
 	return m.searchLyricsWithMpris(query, "", "")
 }
 
 func (m Model) searchLyricsWithMpris(query, mprisArtist, mprisTitle string) tea.Cmd {
+	ctx, gen := m.fetchCtx, m.lookupGen
 	return func() tea.Msg {
-		artist, title, err := m.parser.Parse(query)
+		if alias, ok := m.aliases.Lookup(query, mprisArtist, mprisTitle); ok {
+			return parsedResult{
+				gen:         gen,
+				query:       query,
+				artist:      alias.Artist,
+				title:       alias.Title,
+				mprisArtist: mprisArtist,
+				mprisTitle:  mprisTitle,
+				fromAlias:   true,
+			}
+		}
+
+		artist, title, answered, err := parseQuery(ctx, m.parser, query)
 		if err != nil {
 			return parsedResult{
+				gen:         gen,
+				query:       query,
 				err:         fmt.Errorf("failed to parse: %w", err),
 				mprisArtist: mprisArtist,
 				mprisTitle:  mprisTitle,
@@ -66,19 +93,48 @@ func (m Model) searchLyricsWithMpris(query, mprisArtist, mprisTitle string) tea.
 		}
 
 		return parsedResult{
+			gen:         gen,
+			query:       query,
 			artist:      artist,
 			title:       title,
 			mprisArtist: mprisArtist,
 			mprisTitle:  mprisTitle,
+			provider:    answered.ID(),
 		}
 	}
 }
 
+// parseQuery parses query, also returning the provider that answered:
+// with fallbacks configured, it may not be the first one.
+func parseQuery(ctx context.Context, parser parse.Provider, query string) (artist, title string, answered parse.Provider, err error) {
+	if fp, ok := parser.(*parse.FallbackProvider); ok {
+		return fp.ParseAnswered(ctx, query)
+	}
+	artist, title, err = parser.Parse(ctx, query)
+	return artist, title, parser, err
+}
+
+// trackFor builds the lookup for a parsed song. Lookups started from MPRIS
+// also carry the player's album, duration and file.
+func (m Model) trackFor(artist, title, mprisArtist string) lyrics.Track {
+	track := lyrics.Track{Artist: artist, Title: title}
+	if mprisArtist != "" && mprisArtist == m.mprisArtist {
+		md := player.Metadata{URL: m.mprisURL}
+		track.Album = m.mprisAlbum
+		track.Duration = m.mprisLength
+		track.FilePath = md.FilePath()
+	}
+	return track
+}
+
 func (m Model) fetchLyrics(artist, title, mprisArtist, mprisTitle string) tea.Cmd {
-	return func() tea.Msg {
-		song, err := m.lyricsService.Fetch(artist, title)
+	track := m.trackFor(artist, title, mprisArtist)
+	ctx, gen := m.fetchCtx, m.lookupGen
+	return streamLookup(gen, func(onPartial func(lyrics.Partial)) tea.Msg {
+		song, err := m.lyricsService.Fetch(lyrics.WithProgress(ctx, onPartial), track)
 		if err != nil {
 			return searchResult{
+				gen:         gen,
 				err:         err,
 				mprisArtist: mprisArtist,
 				mprisTitle:  mprisTitle,
@@ -86,27 +142,210 @@ func (m Model) fetchLyrics(artist, title, mprisArtist, mprisTitle string) tea.Cm
 		}
 
 		return searchResult{
+			gen:         gen,
+			song:        song,
+			mprisArtist: mprisArtist,
+			mprisTitle:  mprisTitle,
+		}
+	})
+}
+
+// refetchLyrics walks the provider chain again, ignoring the cache.
+func (m Model) refetchLyrics(artist, title, mprisArtist, mprisTitle string) tea.Cmd {
+	track := m.trackFor(artist, title, mprisArtist)
+	ctx, gen := m.fetchCtx, m.lookupGen
+	return streamLookup(gen, func(onPartial func(lyrics.Partial)) tea.Msg {
+		song, err := m.lyricsService.Refetch(lyrics.WithProgress(ctx, onPartial), track)
+		return searchResult{
+			gen:         gen,
 			song:        song,
 			mprisArtist: mprisArtist,
 			mprisTitle:  mprisTitle,
+			err:         err,
+		}
+	})
+}
+
+// streamLookup runs lookup in the background and delivers its partial
+// lyrics as lyricsProgress messages before its final message. Partials
+// the UI hasn't picked up yet are replaced by newer ones.
+func streamLookup(gen int, lookup func(onPartial func(lyrics.Partial)) tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		ch := make(chan tea.Msg, 1)
+		var mu sync.Mutex
+		done := false
+		go func() {
+			msg := lookup(func(p lyrics.Partial) {
+				mu.Lock()
+				defer mu.Unlock()
+				if done {
+					return
+				}
+				select {
+				case <-ch:
+				default:
+				}
+				ch <- lyricsProgress{gen: gen, partial: p, next: ch}
+			})
+			// a cancelled provider may still report after the lookup ended
+			mu.Lock()
+			done = true
+			mu.Unlock()
+			ch <- msg
+		}()
+		return <-ch
+	}
+}
+
+func waitForLookup(next <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-next
+	}
+}
+
+func (m Model) fetchAnnotations(geniusID int, plainLyrics string) tea.Cmd {
+	ctx, gen := m.fetchCtx, m.lookupGen
+	return func() tea.Msg {
+		annotations, err := m.lyricsService.FetchAnnotations(ctx, geniusID, plainLyrics)
+		return annotationsResult{
+			gen:         gen,
+			geniusID:    geniusID,
+			annotations: annotations,
+			err:         err,
 		}
 	}
 }
 
-func (m Model) fetchAILyrics(query, mprisArtist, mprisTitle string) tea.Cmd {
+// searchSnippet looks for songs whose lyrics contain fragment, in the cache
+// and through the lyric services that can search.
+func (m Model) searchSnippet(fragment string, gen int) tea.Cmd {
 	return func() tea.Msg {
-		artist, title, lyricsText, err := m.parser.FetchLyrics(query)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		candidates, err := m.lyricsService.Search(ctx, fragment)
+		return snippetResult{gen: gen, candidates: candidates, err: err}
+	}
+}
+
+// identifySnippet asks the AI which songs fragment may come from.
+func (m Model) identifySnippet(identifier parse.Identifier, fragment string, gen int) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		found, err := identifier.Identify(ctx, fragment)
+		candidates := make([]lyrics.Candidate, len(found))
+		for i, c := range found {
+			candidates[i] = lyrics.Candidate{Artist: c.Artist, Title: c.Title, Source: lyrics.ProviderAI}
+		}
+		return snippetResult{gen: gen, ai: true, candidates: candidates, err: err}
+	}
+}
+
+// listModels asks the provider being configured in settings for its models.
+func (m Model) listModels(id parse.ProviderID, apiKey string) tea.Cmd {
+	cfg := *m.config
+	cfg.Provider = string(id)
+	cfg.APIKey = apiKey
+	client := m.httpClient.Client
+	return func() tea.Msg {
+		provider, err := parse.NewProviderFromConfig(&cfg, client, nil)
 		if err != nil {
-			return aiLyricsResult{err: err, query: query, mprisArtist: mprisArtist, mprisTitle: mprisTitle}
+			return modelsResult{provider: id, err: err}
+		}
+		lister, ok := provider.(parse.ModelLister)
+		if !ok {
+			return modelsResult{provider: id}
 		}
 
-		return aiLyricsResult{
-			artist:      artist,
-			title:       title,
-			lyrics:      lyricsText,
-			query:       query,
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		models, err := lister.ListModels(ctx)
+		return modelsResult{provider: id, models: models, err: err}
+	}
+}
+
+// warmParser preloads the parser's model, if it has one to load. Errors
+// are left for the next real query to report.
+func (m Model) warmParser() tea.Cmd {
+	warmer, ok := m.parser.(parse.Warmer)
+	if !ok {
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		warmer.Warm(ctx)
+		return nil
+	}
+}
+
+// processQueue retries the lookups queued while offline.
+func (m Model) processQueue() tea.Cmd {
+	return func() tea.Msg {
+		found, err := m.lyricsService.ProcessQueue(context.Background())
+		return queueResult{found: found, err: err}
+	}
+}
+
+// translateLyrics asks the AI for a translation of the loaded lyrics.
+func (m Model) translateLyrics(translator parse.Translator, lines []string, language string) tea.Cmd {
+	gen := m.lookupGen
+	keys := m.songKeys()
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+		defer cancel()
+		translated, err := translator.Translate(ctx, lines, language)
+		return translationResult{gen: gen, language: language, lines: translated, keys: keys, err: err}
+	}
+}
+
+// verifyAILyrics compares the cached AI lyrics of a song with other
+// sources in the background, outliving the lookup so the cache is
+// corrected even after the song changes.
+func (m Model) verifyAILyrics(artist, title, mprisArtist, mprisTitle string) tea.Cmd {
+	gen := m.lookupGen
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		song, replaced, err := m.lyricsService.VerifyAI(ctx, artist, title)
+		return verifyResult{
+			gen:         gen,
+			song:        song,
+			replaced:    replaced,
 			mprisArtist: mprisArtist,
 			mprisTitle:  mprisTitle,
+			err:         err,
+		}
+	}
+}
+
+// refetchCached looks the songs up again without the AI, replacing their
+// cached lyrics when found.
+func (m Model) refetchCached(entries []lyrics.CachedSongEntry) tea.Cmd {
+	return func() tea.Msg {
+		replaced := 0
+		for _, entry := range entries {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if _, err := m.lyricsService.RefetchCached(ctx, entry.Artist, entry.Title); err == nil {
+				replaced++
+			}
+			cancel()
 		}
+		return refetchResult{replaced: replaced, total: len(entries)}
+	}
+}
+
+// startLookup supersedes the lookup in flight, if any: it is cancelled and
+// its results will no longer be displayed.
+func (m Model) startLookup() Model {
+	if m.cancelFetch != nil {
+		m.cancelFetch()
 	}
+	m.fetchCtx, m.cancelFetch = context.WithCancel(context.Background())
+	m.lookupGen++
+	m.streaming = false
+	m.translation = nil
+	m.translating = false
+	m.parseFallback = ""
+	return m
 }
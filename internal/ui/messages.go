@@ -3,7 +3,10 @@ package ui
 import (
 	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
+
 	"lyrics-tui/internal/lyrics"
+	"lyrics-tui/internal/parse"
 )
 
 // tickMsg triggers periodic MPRIS polling.
@@ -12,10 +15,16 @@ type tickMsg time.Time
 // positionTickMsg triggers playback position updates.
 type positionTickMsg time.Time
 
+// queueTickMsg triggers a retry of lookups queued while offline.
+type queueTickMsg time.Time
+
 // mprisData contains currently playing song metadata from MPRIS.
 type mprisData struct {
 	artist string
 	title  string
+	album  string
+	url    string
+	length float64
 	err    error
 }
 
@@ -26,29 +35,92 @@ type playbackPosition struct {
 	err      error
 }
 
-// parsedResult contains the result of parsing a song query.
+// parsedResult contains the result of parsing a song query. fromAlias is
+// set when the alias table already knew the query.
 type parsedResult struct {
+	gen         int
+	query       string
 	artist      string
 	title       string
 	mprisArtist string
 	mprisTitle  string
+	fromAlias   bool
+	provider    parse.ProviderID // the parser that answered
 	err         error
 }
 
 // searchResult contains fetched lyrics.
 type searchResult struct {
+	gen         int
 	song        *lyrics.Song
 	mprisArtist string
 	mprisTitle  string
 	err         error
 }
 
-type aiLyricsResult struct {
-	artist      string
-	title       string
-	lyrics      string
-	query       string
+// snippetResult carries the songs a lyric search found, from the cache and
+// lyric services or, with ai set, from the AI.
+type snippetResult struct {
+	gen        int
+	ai         bool
+	candidates []lyrics.Candidate
+	err        error
+}
+
+// lyricsProgress carries lyrics still streaming in for a lookup. next
+// delivers the lookup's following message, ending with its searchResult.
+type lyricsProgress struct {
+	gen     int
+	partial lyrics.Partial
+	next    <-chan tea.Msg
+}
+
+// annotationsResult contains Genius annotations mapped onto lyric lines.
+type annotationsResult struct {
+	gen         int
+	geniusID    int
+	annotations []lyrics.Annotation
+	err         error
+}
+
+// queueResult reports how many queued songs got lyrics.
+type queueResult struct {
+	found int
+	err   error
+}
+
+// translationResult carries the translation of the lyrics loaded in gen,
+// and the cache entries it belongs to.
+type translationResult struct {
+	gen      int
+	language string
+	lines    []string
+	keys     [][2]string
+	err      error
+}
+
+// verifyResult reports how AI lyrics compared with lyrics found
+// elsewhere. song is the entry as now cached, replaced when the AI's
+// lyrics were wrong.
+type verifyResult struct {
+	gen         int
+	song        *lyrics.Song
+	replaced    bool
 	mprisArtist string
 	mprisTitle  string
 	err         error
 }
+
+// refetchResult reports how many of the cached songs looked up again got
+// found lyrics.
+type refetchResult struct {
+	replaced int
+	total    int
+}
+
+// modelsResult lists the models offered by a provider in the settings modal.
+type modelsResult struct {
+	provider parse.ProviderID
+	models   []parse.ModelInfo
+	err      error
+}
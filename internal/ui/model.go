@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -8,6 +9,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 
 	"lyrics-tui/internal/config"
+	"lyrics-tui/internal/httpclient"
 	"lyrics-tui/internal/lyrics"
 	"lyrics-tui/internal/parse"
 	"lyrics-tui/internal/player"
@@ -17,6 +19,9 @@ type Model struct {
 	lyricsService *lyrics.Service
 	player        player.Player
 	parser        parse.Provider
+	aliases       *parse.Aliases
+	ledger        *parse.Ledger
+	httpClient    *httpclient.Client
 	config        *config.Config
 	version       string
 
@@ -28,6 +33,11 @@ type Model struct {
 	lyrics          string
 	syncedLyrics    []lyrics.Line
 	hasSyncedLyrics bool
+	source          string
+	sourceModel     string
+	// verification of AI lyrics and the confidence in them
+	verification string
+	confidence   float64
 
 	playbackPosition    float64
 	duration            float64
@@ -37,6 +47,7 @@ type Model struct {
 	followMode     bool
 	autoDetectMode bool
 	searching      bool
+	streaming      bool // searching, with AI lyrics arriving
 	ready          bool
 	width          int
 	height         int
@@ -44,38 +55,100 @@ type Model struct {
 	lastDetectedSong string
 	mprisArtist      string
 	mprisTitle       string
+	mprisAlbum       string
+	mprisURL         string
+	mprisLength      float64
 
 	parsedArtist string
 	parsedTitle  string
+	// parsedBy is the parser that answered the last query, empty when it
+	// was found among the aliases
+	parsedBy parse.ProviderID
+	// query to hand to the parser if looking up the player's tags fails
+	parseFallback string
 
 	lastQuery           string
 	lastMprisArtist     string
 	lastMprisTitle      string
 	estimatedTimestamps bool
 
+	// lookup in flight, cancelled when a new one starts; results carry the
+	// generation they were started in and older ones are not displayed
+	fetchCtx    context.Context
+	cancelFetch context.CancelFunc
+	lookupGen   int
+
 	debugInfo string
+	debugOpen bool
 	err       error
 
+	processingQueue   bool
+	notification      string
+	notificationUntil time.Time
+
 	// settings modal
 	settingsOpen        bool
 	settingsCursor      int
 	settingsProviderIdx int
 	settingsModel       textinput.Model
 	settingsAPIKey      textinput.Model
-	settingsAILyrics    bool
+	settingsChain       []config.ChainEntry
+
+	settingsModels        []parse.ModelInfo
+	settingsModelsErr     error
+	settingsModelsLoading bool
 
 	// search modal
 	searchModalOpen bool
 
+	// lyric search of the search modal, toggled with Tab: the query is a
+	// fragment of the lyrics and the songs found are picked from a list
+	searchByLyrics    bool
+	snippetQuery      string
+	snippetCandidates []lyrics.Candidate
+	snippetCursor     int
+	snippetGen        int
+	snippetSearching  string // what is being searched, empty when idle
+	snippetErr        error
+
+	// translation of the loaded lyrics, one entry per lyrics line, shown
+	// next to or under the lyrics while showTranslation is set
+	translation     []string
+	showTranslation bool
+	translating     bool
+
+	// annotations panel
+	geniusID           int
+	annotations        []lyrics.Annotation
+	annotationsOpen    bool
+	annotationsLoading bool
+	annotationsErr     error
+	annotationLine     int
+	annotationIdx      int
+	// plain lyrics line of each synced line, as annotations are placed on
+	// the plain lyrics
+	plainLines []int
+
 	// cached songs modal
 	cachedSongsModalOpen bool
 	cachedSongs          []lyrics.CachedSongEntry
 	cachedSongsFiltered  []lyrics.CachedSongEntry
 	cachedSongsCursor    int
 	cachedSongsFilter    textinput.Model
+	// cachedSource narrows the list to one source when cachedBySource is
+	// set, cycled with Ctrl+F
+	cachedSource    string
+	cachedBySource  bool
+	refetchingCache bool
+
+	// aliases list of the cached songs modal, toggled with Tab
+	aliasesOpen     bool
+	aliasesFiltered []parse.Alias
+	aliasEditing    bool
+	aliasEdit       textinput.Model
 }
 
-func NewModel(lyricsService *lyrics.Service, player player.Player, parser parse.Provider, cfg *config.Config, version string) Model {
+func NewModel(lyricsService *lyrics.Service, player player.Player, parser parse.Provider, aliases *parse.Aliases, ledger *parse.Ledger, httpClient *httpclient.Client, cfg *config.Config, version string) Model {
 	ti := textinput.New()
 	ti.Placeholder = "Type song name..."
 	ti.CharLimit = 200
@@ -101,21 +174,31 @@ func NewModel(lyricsService *lyrics.Service, player player.Player, parser parse.
 	cf.CharLimit = 100
 	cf.Width = 40
 
+	ae := textinput.New()
+	ae.Placeholder = "Artist - Title"
+	ae.CharLimit = 200
+	ae.Width = 40
+
 	return Model{
 		lyricsService:     lyricsService,
 		player:            player,
 		parser:            parser,
+		aliases:           aliases,
+		ledger:            ledger,
+		httpClient:        httpClient,
 		config:            cfg,
 		version:           version,
 		input:             ti,
 		viewport:          vp,
 		followMode:        false,
+		fetchCtx:          context.Background(),
 		settingsModel:     sm,
 		settingsAPIKey:    sa,
 		cachedSongsFilter: cf,
+		aliasEdit:         ae,
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(textinput.Blink, tickEverySecond(), tickPosition())
+	return tea.Batch(textinput.Blink, tickEverySecond(), tickPosition(), tickQueue(), m.warmParser())
 }
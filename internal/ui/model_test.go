@@ -0,0 +1,239 @@
+package ui
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"lyrics-tui/internal/config"
+	"lyrics-tui/internal/lyrics"
+	"lyrics-tui/internal/parse"
+)
+
+var errTest = errors.New("test failure")
+
+// newTestModel returns a model with a song on screen, loaded by the second
+// lookup, backed by an empty cache and no lyrics providers.
+func newTestModel(t *testing.T, parser parse.Provider) Model {
+	t.Helper()
+	dir := t.TempDir()
+	service := lyrics.NewService(lyrics.NewRegistry(), nil, lyrics.NewCache(filepath.Join(dir, "cache")), lyrics.NewQueue(filepath.Join(dir, "queue.json")))
+	aliases := parse.NewAliases(filepath.Join(dir, "aliases.json"))
+	m := NewModel(service, nil, parser, aliases, nil, nil, config.DefaultConfig(), "test")
+	m.width, m.height = 120, 40
+
+	m = m.startLookup()
+	m = m.startLookup()
+	m.artist, m.title = "Current Artist", "Current Song"
+	m.parsedArtist, m.parsedTitle = "Current Artist", "Current Song"
+	m.mprisArtist, m.mprisTitle = "Current Artist", "Current Song"
+	m.lyrics = "current lyrics"
+	m.syncedLyrics = []lyrics.Line{{Timestamp: 1, Text: "current lyrics"}}
+	m.hasSyncedLyrics = true
+	m.source = lyrics.ProviderLRCLIB
+	m.geniusID = 42
+	m.annotations = []lyrics.Annotation{{Fragment: "current", Body: "current annotation"}}
+	m.searching = true
+	m.viewport.SetContent(m.renderSyncedLyrics())
+	return m
+}
+
+// viewState is what a lookup's result may change on screen.
+type viewState struct {
+	artist, title             string
+	parsedArtist, parsedTitle string
+	lyrics                    string
+	syncedLyrics              []lyrics.Line
+	source                    string
+	annotations               []lyrics.Annotation
+	searching, streaming      bool
+	view                      string
+}
+
+func stateOf(m Model) viewState {
+	return viewState{
+		artist:       m.artist,
+		title:        m.title,
+		parsedArtist: m.parsedArtist,
+		parsedTitle:  m.parsedTitle,
+		lyrics:       m.lyrics,
+		syncedLyrics: m.syncedLyrics,
+		source:       m.source,
+		annotations:  m.annotations,
+		searching:    m.searching,
+		streaming:    m.streaming,
+		view:         m.viewport.View(),
+	}
+}
+
+func update(t *testing.T, m Model, msg tea.Msg) Model {
+	t.Helper()
+	next, _ := m.Update(msg)
+	return next.(Model)
+}
+
+func TestStaleResultsAreNotDisplayed(t *testing.T) {
+	stale := 1
+	oldSong := &lyrics.Song{
+		Artist:       "Old Artist",
+		Title:        "Old Song",
+		Lyrics:       "old lyrics",
+		SyncedLyrics: []lyrics.Line{{Timestamp: 1, Text: "old lyrics"}},
+		Source:       lyrics.ProviderGenius,
+	}
+	tests := []struct {
+		name string
+		msg  tea.Msg
+	}{
+		{"parsedResult", parsedResult{gen: stale, query: "old song", artist: "Old Artist", title: "Old Song"}},
+		{"parsedResult error", parsedResult{gen: stale, err: errTest}},
+		{"lyricsProgress", lyricsProgress{gen: stale, partial: lyrics.Partial{Artist: "Old Artist", Title: "Old Song", Lyrics: "old ly", Source: lyrics.ProviderAI}}},
+		{"searchResult", searchResult{gen: stale, song: oldSong, mprisArtist: "Old Artist", mprisTitle: "Old Song"}},
+		{"searchResult error", searchResult{gen: stale, err: errTest}},
+		{"annotationsResult", annotationsResult{gen: stale, geniusID: 42, annotations: []lyrics.Annotation{{Fragment: "old", Body: "old annotation"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, parse.NewHeuristicProvider())
+			before := stateOf(m)
+			m = update(t, m, tt.msg)
+			if after := stateOf(m); !reflect.DeepEqual(after, before) {
+				t.Errorf("stale %s changed the view:\n%+v\nwant\n%+v", tt.name, after, before)
+			}
+		})
+	}
+}
+
+func TestStaleSearchResultIsOnlyCached(t *testing.T) {
+	m := newTestModel(t, parse.NewHeuristicProvider())
+	song := &lyrics.Song{Artist: "Old Artist", Title: "Old Song", Lyrics: "old lyrics", Source: lyrics.ProviderGenius}
+	m = update(t, m, searchResult{gen: 1, song: song, mprisArtist: "Old Player Artist", mprisTitle: "Old Player Title"})
+
+	cached, err := m.lyricsService.LoadFromCache("Old Player Artist", "Old Player Title")
+	if err != nil {
+		t.Fatalf("stale result was not cached under the player's tags: %v", err)
+	}
+	if cached.Lyrics != "old lyrics" {
+		t.Errorf("cached lyrics = %q, want the stale result's", cached.Lyrics)
+	}
+	if _, err := m.lyricsService.LoadFromCache("Current Artist", "Current Song"); err == nil {
+		t.Error("stale result was cached under the current song")
+	}
+}
+
+func TestStaleParsedResultRecordsNoAlias(t *testing.T) {
+	m := newTestModel(t, parse.NewFallbackProvider(parse.NewHeuristicProvider()))
+	m = update(t, m, parsedResult{gen: 1, query: "old song", artist: "Old Artist", title: "Old Song"})
+	if aliases := m.aliases.List(); len(aliases) != 0 {
+		t.Errorf("stale parse recorded aliases %+v", aliases)
+	}
+}
+
+func TestCurrentSearchResultIsDisplayed(t *testing.T) {
+	m := newTestModel(t, parse.NewHeuristicProvider())
+	song := &lyrics.Song{
+		Artist:          "New Artist",
+		Title:           "New Song",
+		SyncedLyrics:    []lyrics.Line{{Timestamp: 1, Text: "new lyrics"}},
+		HasSyncedLyrics: true,
+		Source:          lyrics.ProviderLRCLIB,
+	}
+	m = update(t, m, searchResult{gen: m.lookupGen, song: song, mprisArtist: "New Artist", mprisTitle: "New Song"})
+
+	if m.artist != "New Artist" || m.searching || !reflect.DeepEqual(m.syncedLyrics, song.SyncedLyrics) {
+		t.Errorf("current result not displayed: artist %q, searching %v, lines %+v", m.artist, m.searching, m.syncedLyrics)
+	}
+	if _, err := m.lyricsService.LoadFromCache("New Artist", "New Song"); err != nil {
+		t.Errorf("current result not cached: %v", err)
+	}
+}
+
+func TestParsedResultRecordsAnsweringProvider(t *testing.T) {
+	m := newTestModel(t, parse.NewFallbackProvider(parse.NewHeuristicProvider(), parse.NewOllamaProvider("", "", nil)))
+
+	m = update(t, m, parsedResult{gen: m.lookupGen, query: "stand by me", artist: "Ben E. King", title: "Stand by Me", provider: parse.ProviderHeuristic})
+	if aliases := m.aliases.List(); len(aliases) != 0 {
+		t.Fatalf("heuristic split recorded as aliases %+v", aliases)
+	}
+
+	m = update(t, m, parsedResult{gen: m.lookupGen, query: "stand by me", artist: "Ben E. King", title: "Stand by Me", provider: parse.ProviderOllama})
+	aliases := m.aliases.List()
+	if len(aliases) != 1 || aliases[0].Provider != parse.ProviderOllama {
+		t.Errorf("aliases = %+v, want one from ollama", aliases)
+	}
+	if m.parsedBy != parse.ProviderOllama {
+		t.Errorf("parsedBy = %q, want ollama", m.parsedBy)
+	}
+}
+
+func TestResultsKeepCachedOffset(t *testing.T) {
+	song := &lyrics.Song{Artist: "Artist", Title: "Song", Lyrics: "lyrics", Source: lyrics.ProviderAI}
+	verified := &lyrics.Song{Artist: "Artist", Title: "Song", Lyrics: "lyrics", Source: lyrics.ProviderAI, Verification: lyrics.Verified}
+
+	tests := []struct {
+		name       string
+		msg        func(m Model) tea.Msg
+		wantOffset float64
+		wantShown  float64
+	}{
+		{"stale search", func(m Model) tea.Msg {
+			return searchResult{gen: m.lookupGen - 1, song: song, mprisArtist: "Artist", mprisTitle: "Song"}
+		}, 1.5, -0.3},
+		{"current search", func(m Model) tea.Msg {
+			return searchResult{gen: m.lookupGen, song: song, mprisArtist: "Artist", mprisTitle: "Song"}
+		}, 1.5, 1.5},
+		{"stale verification", func(m Model) tea.Msg {
+			return verifyResult{gen: m.lookupGen - 1, song: verified, mprisArtist: "Artist", mprisTitle: "Song"}
+		}, 1.5, -0.3},
+		{"current verification", func(m Model) tea.Msg {
+			return verifyResult{gen: m.lookupGen, song: verified, mprisArtist: "Artist", mprisTitle: "Song"}
+		}, -0.3, -0.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, parse.NewHeuristicProvider())
+			if err := m.lyricsService.SaveToCache("Artist", "Song", song, 1.5); err != nil {
+				t.Fatal(err)
+			}
+			m.offset = -0.3
+
+			m = update(t, m, tt.msg(m))
+			cached, err := m.lyricsService.LoadFromCache("Artist", "Song")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cached.Offset != tt.wantOffset {
+				t.Errorf("cached offset = %v, want %v", cached.Offset, tt.wantOffset)
+			}
+			if m.offset != tt.wantShown {
+				t.Errorf("offset = %v, want %v", m.offset, tt.wantShown)
+			}
+		})
+	}
+}
+
+func TestVerifyResultReplacesLyrics(t *testing.T) {
+	tests := []struct {
+		verification string
+		want         string
+	}{
+		{lyrics.Verified, "AI lyrics verified, replaced with LRCLIB's"},
+		{"", "AI lyrics were wrong, replaced with LRCLIB's"},
+	}
+	for _, tt := range tests {
+		m := newTestModel(t, parse.NewHeuristicProvider())
+		found := &lyrics.Song{Artist: "Artist", Title: "Song", Lyrics: "found lyrics", Source: lyrics.ProviderLRCLIB, Verification: tt.verification}
+		m = update(t, m, verifyResult{gen: m.lookupGen, song: found, replaced: true, mprisArtist: "Artist", mprisTitle: "Song"})
+		if m.lyrics != "found lyrics" || m.source != lyrics.ProviderLRCLIB {
+			t.Errorf("showing %q from %q, want the found lyrics", m.lyrics, m.source)
+		}
+		if m.notification != tt.want {
+			t.Errorf("notification = %q, want %q", m.notification, tt.want)
+		}
+	}
+}
@@ -31,6 +31,13 @@ var (
 	warningStyle = lipgloss.NewStyle().
 			Foreground(yellow)
 
+	sectionStyle = lipgloss.NewStyle().
+			Italic(true).
+			Foreground(mauve)
+
+	annotatedStyle = lipgloss.NewStyle().
+			Underline(true)
+
 	boxStyle = lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
 			BorderForeground(lavender).
@@ -1,12 +1,16 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"lyrics-tui/internal/config"
+	"lyrics-tui/internal/httpclient"
 	"lyrics-tui/internal/lyrics"
 	"lyrics-tui/internal/parse"
 )
@@ -25,6 +29,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case positionTickMsg:
 		return m, tea.Batch(m.getPlaybackPosition(), tickPosition())
 
+	case queueTickMsg:
+		return m.handleQueueTick()
+
+	case queueResult:
+		return m.handleQueueResult(msg)
+
 	case playbackPosition:
 		return m.handlePlaybackPosition(msg)
 
@@ -34,11 +44,29 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case parsedResult:
 		return m.handleParsedResult(msg)
 
+	case lyricsProgress:
+		return m.handleLyricsProgress(msg)
+
 	case searchResult:
 		return m.handleSearchResult(msg)
 
-	case aiLyricsResult:
-		return m.handleAILyricsResult(msg)
+	case snippetResult:
+		return m.handleSnippetResult(msg)
+
+	case refetchResult:
+		return m.handleRefetchResult(msg)
+
+	case verifyResult:
+		return m.handleVerifyResult(msg)
+
+	case translationResult:
+		return m.handleTranslationResult(msg)
+
+	case annotationsResult:
+		return m.handleAnnotationsResult(msg)
+
+	case modelsResult:
+		return m.handleModelsResult(msg)
 	}
 
 	if m.settingsOpen {
@@ -55,12 +83,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	if m.cachedSongsModalOpen {
-		var cfCmd tea.Cmd
+		var cfCmd, aeCmd tea.Cmd
 		m.cachedSongsFilter, cfCmd = m.cachedSongsFilter.Update(msg)
-		return m, cfCmd
+		m.aliasEdit, aeCmd = m.aliasEdit.Update(msg)
+		return m, tea.Batch(cfCmd, aeCmd)
 	}
 
 	m.viewport, _ = m.viewport.Update(msg)
+	m = m.refreshAnnotatedLyrics()
 	return m, nil
 }
 
@@ -74,6 +104,15 @@ func (m Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if m.cachedSongsModalOpen {
 		return m.handleCachedSongsKeyMsg(msg)
 	}
+	if m.debugOpen {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc", "ctrl+d":
+			m.debugOpen = false
+		}
+		return m, nil
+	}
 
 	switch msg.String() {
 	case "ctrl+c", "esc":
@@ -82,6 +121,10 @@ func (m Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "ctrl+o":
 		return m.openSettings()
 
+	case "ctrl+d":
+		m.debugOpen = true
+		return m, nil
+
 	case "/":
 		m.searchModalOpen = true
 		m.input.SetValue("")
@@ -92,24 +135,31 @@ func (m Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.cachedSongs = m.lyricsService.ListAllCached()
 		m.cachedSongsFiltered = m.cachedSongs
 		m.cachedSongsCursor = 0
+		m.cachedBySource = false
 		m.cachedSongsFilter.SetValue("")
 		m.cachedSongsFilter.Focus()
 		m.cachedSongsModalOpen = true
+		m.aliasesOpen = false
+		m.aliasEditing = false
 		return m, nil
 
 	case "ctrl+r":
-		if m.lastQuery == "" || !m.config.AILyrics {
+		if m.lastQuery == "" {
 			return m, nil
 		}
+		m = m.startLookup()
 		m.searching = true
 		m.viewport.SetContent("Retrying...")
-		return m, m.fetchAILyrics(m.lastQuery, m.lastMprisArtist, m.lastMprisTitle)
+		if m.parsedArtist == "" || m.parsedTitle == "" {
+			return m, m.searchLyricsWithMpris(m.lastQuery, m.lastMprisArtist, m.lastMprisTitle)
+		}
+		return m, m.refetchLyrics(m.parsedArtist, m.parsedTitle, m.lastMprisArtist, m.lastMprisTitle)
 
 	case "tab":
 		m.autoDetectMode = !m.autoDetectMode
 		if m.autoDetectMode {
 			m.followMode = true
-			return m, tea.Batch(m.detectCurrentSong(), tickEverySecond())
+			return m, tea.Batch(m.detectCurrentSong(), tickEverySecond(), m.warmParser())
 		}
 		m.followMode = false
 		return m, nil
@@ -121,6 +171,26 @@ func (m Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case "o":
+		return m.toggleOfflineOnly()
+
+	case "a":
+		return m.toggleAnnotations()
+
+	case "t":
+		return m.toggleTranslation()
+
+	case "n":
+		if m.annotationsOpen {
+			line := m.focusLine()
+			if line != m.annotationLine {
+				m.annotationLine = line
+				m.annotationIdx = 0
+			}
+			m.annotationIdx++
+		}
+		return m, nil
+
 	case "+", "=":
 		if m.hasSyncedLyrics {
 			m.offset += 0.1
@@ -137,8 +207,10 @@ func (m Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "up", "k":
 		m.viewport, _ = m.viewport.Update(msg)
+		m = m.refreshAnnotatedLyrics()
 	case "down", "j":
 		m.viewport, _ = m.viewport.Update(msg)
+		m = m.refreshAnnotatedLyrics()
 	}
 
 	return m, nil
@@ -153,14 +225,45 @@ func (m Model) handleSearchModalKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "esc":
 		m.searchModalOpen = false
 		m.input.Blur()
+		m = m.resetSnippetSearch()
+		return m, nil
+	case "tab":
+		m.searchByLyrics = !m.searchByLyrics
+		m.input.Placeholder = "Type song name..."
+		if m.searchByLyrics {
+			m.input.Placeholder = "Type a line of the lyrics..."
+		}
+		m = m.resetSnippetSearch()
+		return m, nil
+	case "up":
+		if m.snippetCursor > 0 {
+			m.snippetCursor--
+		}
+		return m, nil
+	case "down":
+		if m.snippetCursor < len(m.snippetCandidates)-1 {
+			m.snippetCursor++
+		}
 		return m, nil
+	case "ctrl+g":
+		return m.identifySnippetWithAI()
 	case "enter":
 		query := m.input.Value()
 		if query == "" {
 			return m, nil
 		}
+		if m.searchByLyrics {
+			if query == m.snippetQuery && len(m.snippetCandidates) > 0 {
+				return m.pickCandidate(m.snippetCandidates[m.snippetCursor])
+			}
+			m = m.resetSnippetSearch()
+			m.snippetQuery = query
+			m.snippetSearching = "Searching cached lyrics and LRCLIB..."
+			return m, m.searchSnippet(query, m.snippetGen)
+		}
 		m.searchModalOpen = false
 		m.input.Blur()
+		m = m.startLookup()
 		m.searching = true
 		m.lastQuery = query
 		m.lastMprisArtist = ""
@@ -174,9 +277,83 @@ func (m Model) handleSearchModalKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// resetSnippetSearch forgets the lyric search's results, ignoring those
+// still on their way.
+func (m Model) resetSnippetSearch() Model {
+	m.snippetGen++
+	m.snippetQuery = ""
+	m.snippetCandidates = nil
+	m.snippetCursor = 0
+	m.snippetSearching = ""
+	m.snippetErr = nil
+	return m
+}
+
+// canIdentify reports whether the AI may be asked which song a lyric
+// fragment comes from.
+func (m Model) canIdentify() bool {
+	_, ok := m.parser.(parse.Identifier)
+	return ok && !m.lyricsService.OfflineOnly() && !m.budgetReached()
+}
+
+// identifySnippetWithAI adds the AI's guesses to the lyric search's
+// results.
+func (m Model) identifySnippetWithAI() (tea.Model, tea.Cmd) {
+	identifier, ok := m.parser.(parse.Identifier)
+	if !m.searchByLyrics || m.snippetQuery == "" || m.snippetSearching != "" || !ok || !m.canIdentify() {
+		return m, nil
+	}
+	m.snippetSearching = fmt.Sprintf("Asking %s...", m.parser.Name())
+	m.snippetErr = nil
+	return m, m.identifySnippet(identifier, m.snippetQuery, m.snippetGen)
+}
+
+func (m Model) handleSnippetResult(msg snippetResult) (tea.Model, tea.Cmd) {
+	if msg.gen != m.snippetGen {
+		return m, nil
+	}
+	m.snippetSearching = ""
+	m.snippetErr = msg.err
+
+	for _, c := range msg.candidates {
+		dup := false
+		for _, existing := range m.snippetCandidates {
+			if strings.EqualFold(c.Artist, existing.Artist) && strings.EqualFold(c.Title, existing.Title) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			m.snippetCandidates = append(m.snippetCandidates, c)
+		}
+	}
+	return m, nil
+}
+
+// pickCandidate closes the search modal and looks up the picked song.
+func (m Model) pickCandidate(c lyrics.Candidate) (tea.Model, tea.Cmd) {
+	m.searchModalOpen = false
+	m.input.Blur()
+	m = m.resetSnippetSearch()
+
+	m = m.startLookup()
+	m.searching = true
+	m.lastQuery = c.Artist + " " + c.Title
+	m.lastMprisArtist = ""
+	m.lastMprisTitle = ""
+	m.parsedArtist = c.Artist
+	m.parsedTitle = c.Title
+	m.viewport.SetContent(fmt.Sprintf("Fetching lyrics for\n%s - %s...", c.Artist, c.Title))
+	return m, m.fetchLyrics(c.Artist, c.Title, "", "")
+}
+
 // --- cached songs modal ---
 
 func (m Model) handleCachedSongsKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.aliasEditing {
+		return m.handleAliasEditKeyMsg(msg)
+	}
+
 	switch msg.String() {
 	case "ctrl+c":
 		return m, tea.Quit
@@ -184,17 +361,58 @@ func (m Model) handleCachedSongsKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.cachedSongsModalOpen = false
 		m.cachedSongsFilter.Blur()
 		return m, nil
+	case "tab":
+		m.aliasesOpen = !m.aliasesOpen
+		m.aliasesFiltered = m.filterAliases()
+		m.cachedSongsCursor = 0
+		return m, nil
 	case "up":
 		if m.cachedSongsCursor > 0 {
 			m.cachedSongsCursor--
 		}
 		return m, nil
 	case "down":
-		if m.cachedSongsCursor < len(m.cachedSongsFiltered)-1 {
+		if m.cachedSongsCursor < m.cachedListLen()-1 {
 			m.cachedSongsCursor++
 		}
 		return m, nil
+	case "ctrl+f":
+		if m.aliasesOpen {
+			return m, nil
+		}
+		m = m.cycleCachedSource()
+		m.cachedSongsFiltered = m.filterCachedSongs()
+		m.cachedSongsCursor = 0
+		return m, nil
+	case "ctrl+r":
+		if m.aliasesOpen || m.refetchingCache || len(m.cachedSongsFiltered) == 0 || m.lyricsService.OfflineOnly() {
+			return m, nil
+		}
+		m.refetchingCache = true
+		return m, m.refetchCached(m.cachedSongsFiltered)
+	case "delete", "ctrl+x":
+		if !m.aliasesOpen || len(m.aliasesFiltered) == 0 {
+			return m, nil
+		}
+		m.aliases.Remove(m.aliasesFiltered[m.cachedSongsCursor])
+		m.aliasesFiltered = m.filterAliases()
+		if m.cachedSongsCursor >= len(m.aliasesFiltered) && m.cachedSongsCursor > 0 {
+			m.cachedSongsCursor--
+		}
+		return m, nil
 	case "enter":
+		if m.aliasesOpen {
+			if len(m.aliasesFiltered) == 0 {
+				return m, nil
+			}
+			alias := m.aliasesFiltered[m.cachedSongsCursor]
+			m.aliasEditing = true
+			m.aliasEdit.SetValue(alias.Artist + " - " + alias.Title)
+			m.aliasEdit.CursorEnd()
+			m.aliasEdit.Focus()
+			m.cachedSongsFilter.Blur()
+			return m, nil
+		}
 		if len(m.cachedSongsFiltered) == 0 {
 			return m, nil
 		}
@@ -207,10 +425,15 @@ func (m Model) handleCachedSongsKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		m = m.startLookup()
 		m.artist = cached.Artist
 		m.title = cached.Title
 		m.lyrics = cached.Lyrics
 		m.syncedLyrics = cached.SyncedLyrics
+		m.source = cached.Source
+		m.sourceModel = cached.Model
+		m.verification = cached.Verification
+		m.confidence = cached.Confidence
 		m.hasSyncedLyrics = cached.HasSyncedLyrics
 		m.offset = cached.Offset
 		m.parsedArtist = cached.Artist
@@ -218,31 +441,96 @@ func (m Model) handleCachedSongsKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.playbackPosition = 0
 		m.searching = false
 
+		m = m.loadTranslation()
+
+		var annCmd tea.Cmd
+		m, annCmd = m.resetAnnotations(cached.GeniusID)
+
 		if m.hasSyncedLyrics {
 			m.viewport.SetContent(m.renderSyncedLyrics())
 		} else {
-			m.viewport.SetContent(cached.Lyrics)
+			m.viewport.SetContent(m.renderPlainLyrics(cached.Lyrics))
 		}
 
 		m.cachedSongsModalOpen = false
 		m.cachedSongsFilter.Blur()
-		return m, nil
+		return m, annCmd
 	}
 
 	var cmd tea.Cmd
 	m.cachedSongsFilter, cmd = m.cachedSongsFilter.Update(msg)
 	m.cachedSongsFiltered = m.filterCachedSongs()
+	m.aliasesFiltered = m.filterAliases()
 	m.cachedSongsCursor = 0
 	return m, cmd
 }
 
+// handleAliasEditKeyMsg edits the song an alias points to, typed as
+// "Artist - Title".
+func (m Model) handleAliasEditKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.aliasEditing = false
+		m.aliasEdit.Blur()
+		m.cachedSongsFilter.Focus()
+		return m, nil
+	case "enter":
+		parts := strings.SplitN(m.aliasEdit.Value(), " - ", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return m, nil
+		}
+		alias := m.aliasesFiltered[m.cachedSongsCursor]
+		alias.Artist = strings.TrimSpace(parts[0])
+		alias.Title = strings.TrimSpace(parts[1])
+		alias.Provider = "user"
+		alias.Identified = time.Now()
+		m.aliases.Set(alias)
+
+		m.aliasEditing = false
+		m.aliasEdit.Blur()
+		m.cachedSongsFilter.Focus()
+		m.aliasesFiltered = m.filterAliases()
+		m.cachedSongsCursor = 0
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.aliasEdit, cmd = m.aliasEdit.Update(msg)
+	return m, cmd
+}
+
+func (m Model) filterAliases() []parse.Alias {
+	query := strings.ToLower(m.cachedSongsFilter.Value())
+	var filtered []parse.Alias
+	for _, alias := range m.aliases.List() {
+		haystack := strings.ToLower(alias.Raw() + " " + alias.Artist + " " + alias.Title)
+		if strings.Contains(haystack, query) {
+			filtered = append(filtered, alias)
+		}
+	}
+	return filtered
+}
+
+// cachedListLen is the length of the list shown in the cached songs modal.
+func (m Model) cachedListLen() int {
+	if m.aliasesOpen {
+		return len(m.aliasesFiltered)
+	}
+	return len(m.cachedSongsFiltered)
+}
+
 func (m Model) filterCachedSongs() []lyrics.CachedSongEntry {
 	query := strings.ToLower(m.cachedSongsFilter.Value())
-	if query == "" {
+	if query == "" && !m.cachedBySource {
 		return m.cachedSongs
 	}
 	var filtered []lyrics.CachedSongEntry
 	for _, entry := range m.cachedSongs {
+		if m.cachedBySource && entry.Source != m.cachedSource {
+			continue
+		}
 		haystack := strings.ToLower(entry.Artist + " " + entry.Title)
 		if strings.Contains(haystack, query) {
 			filtered = append(filtered, entry)
@@ -251,6 +539,58 @@ func (m Model) filterCachedSongs() []lyrics.CachedSongEntry {
 	return filtered
 }
 
+// cachedSources lists the sources of the cached songs, by name.
+func (m Model) cachedSources() []string {
+	seen := map[string]bool{}
+	var sources []string
+	for _, entry := range m.cachedSongs {
+		if !seen[entry.Source] {
+			seen[entry.Source] = true
+			sources = append(sources, entry.Source)
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sourceName(sources[i]) < sourceName(sources[j])
+	})
+	return sources
+}
+
+// cycleCachedSource narrows the cached songs to the next source, after
+// the last one listing them all again.
+func (m Model) cycleCachedSource() Model {
+	sources := m.cachedSources()
+	next := 0
+	if m.cachedBySource {
+		next = len(sources)
+		for i, source := range sources {
+			if source == m.cachedSource {
+				next = i + 1
+			}
+		}
+	}
+	if next >= len(sources) {
+		m.cachedBySource = false
+		return m
+	}
+	m.cachedSource, m.cachedBySource = sources[next], true
+	return m
+}
+
+func (m Model) handleRefetchResult(msg refetchResult) (tea.Model, tea.Cmd) {
+	m.refetchingCache = false
+	m.notification = fmt.Sprintf("Found lyrics for %d of %d songs", msg.replaced, msg.total)
+	m.notificationUntil = time.Now().Add(10 * time.Second)
+
+	if m.cachedSongsModalOpen {
+		m.cachedSongs = m.lyricsService.ListAllCached()
+		m.cachedSongsFiltered = m.filterCachedSongs()
+		if m.cachedSongsCursor >= len(m.cachedSongsFiltered) {
+			m.cachedSongsCursor = 0
+		}
+	}
+	return m, nil
+}
+
 // --- settings modal ---
 
 func (m Model) openSettings() (tea.Model, tea.Cmd) {
@@ -266,31 +606,83 @@ func (m Model) openSettings() (tea.Model, tea.Cmd) {
 
 	m.settingsModel.SetValue(m.config.Model)
 	m.settingsAPIKey.SetValue(m.config.APIKey)
-	m.settingsAILyrics = m.config.AILyrics
+	m.settingsChain = append([]config.ChainEntry(nil), m.config.LyricsChain...)
 	m.settingsModel.Blur()
 	m.settingsAPIKey.Blur()
 
+	return m.refreshSettingsModels()
+}
+
+// refreshSettingsModels starts listing the models of the provider selected
+// in settings, for providers that can list them.
+func (m Model) refreshSettingsModels() (Model, tea.Cmd) {
+	m.settingsModels = nil
+	m.settingsModelsErr = nil
+	m.settingsModelsLoading = true
+	return m, m.listModels(parse.AllProviders[m.settingsProviderIdx], m.settingsAPIKey.Value())
+}
+
+func (m Model) handleModelsResult(msg modelsResult) (tea.Model, tea.Cmd) {
+	if !m.settingsOpen || msg.provider != parse.AllProviders[m.settingsProviderIdx] {
+		return m, nil
+	}
+
+	m.settingsModelsLoading = false
+	m.settingsModels = msg.models
+	m.settingsModelsErr = msg.err
+	if m.settingsModel.Value() == "" && len(msg.models) > 0 {
+		m.settingsModel.SetValue(msg.models[0].Name)
+	}
 	return m, nil
 }
 
-func (m Model) settingsMaxField() int {
-	providerID := parse.AllProviders[m.settingsProviderIdx]
-	if providerID != parse.ProviderOllama {
-		return 4
+// cycleSettingsModel picks the previous or next listed model.
+func (m Model) cycleSettingsModel(delta int) Model {
+	current := -1
+	for i, model := range m.settingsModels {
+		if model.Name == m.settingsModel.Value() {
+			current = i
+			break
+		}
 	}
-	return 3
+
+	next := (current + delta + len(m.settingsModels)) % len(m.settingsModels)
+	if current < 0 && delta < 0 {
+		next = len(m.settingsModels) - 1
+	}
+	m.settingsModel.SetValue(m.settingsModels[next].Name)
+	m.settingsModel.CursorEnd()
+	return m
+}
+
+func (m Model) settingsHasAPIKey() bool {
+	id := parse.AllProviders[m.settingsProviderIdx]
+	return id != parse.ProviderOllama && id != parse.ProviderHeuristic
 }
 
-func (m Model) settingsAILyricsField() int {
-	providerID := parse.AllProviders[m.settingsProviderIdx]
-	if providerID != parse.ProviderOllama {
+// settingsChainStart is the cursor position of the first lyrics provider row.
+func (m Model) settingsChainStart() int {
+	if m.settingsHasAPIKey() {
 		return 3
 	}
 	return 2
 }
 
+// settingsChainIndex returns the lyrics provider row under the cursor, or -1.
+func (m Model) settingsChainIndex() int {
+	i := m.settingsCursor - m.settingsChainStart()
+	if i < 0 || i >= len(m.settingsChain) {
+		return -1
+	}
+	return i
+}
+
 func (m Model) settingsClearCacheField() int {
-	return m.settingsMaxField()
+	return m.settingsChainStart() + len(m.settingsChain)
+}
+
+func (m Model) settingsMaxField() int {
+	return m.settingsClearCacheField()
 }
 
 func (m Model) handleSettingsKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
@@ -313,31 +705,41 @@ func (m Model) handleSettingsKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.config.Provider = string(parse.AllProviders[m.settingsProviderIdx])
 		m.config.Model = m.settingsModel.Value()
 		m.config.APIKey = m.settingsAPIKey.Value()
-		m.config.AILyrics = m.settingsAILyrics
+		m.config.LyricsChain = m.settingsChain
 		m.config.Save()
 
-		newParser, err := parse.NewProviderFromConfig(m.config)
+		newParser, err := parse.NewProviderFromConfig(m.config, m.httpClient.Client, m.ledger)
 		if err == nil {
 			m.parser = newParser
+			m.lyricsService.Registry().Register(lyrics.ProviderAI, lyrics.NewAIProvider(newParser))
 		}
+		m.lyricsService.SetChain(m.config.EnabledProviders())
 
 		m.settingsOpen = false
 		return m, nil
 
 	case "tab", "down":
+		leavingKey := m.settingsCursor == 2 && m.settingsHasAPIKey()
 		m.settingsCursor++
 		if m.settingsCursor > maxField {
 			m.settingsCursor = 0
 		}
 		m = m.focusSettingsField()
+		if leavingKey {
+			return m.refreshSettingsModels()
+		}
 		return m, nil
 
 	case "shift+tab", "up":
+		leavingKey := m.settingsCursor == 2 && m.settingsHasAPIKey()
 		m.settingsCursor--
 		if m.settingsCursor < 0 {
 			m.settingsCursor = maxField
 		}
 		m = m.focusSettingsField()
+		if leavingKey {
+			return m.refreshSettingsModels()
+		}
 		return m, nil
 
 	case "left", "right":
@@ -360,19 +762,47 @@ func (m Model) handleSettingsKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			if m.settingsCursor > newMax {
 				m.settingsCursor = newMax
 			}
+			return m.refreshSettingsModels()
+		}
+		if m.settingsCursor == 1 && len(m.settingsModels) > 0 {
+			if msg.String() == "left" {
+				return m.cycleSettingsModel(-1), nil
+			}
+			return m.cycleSettingsModel(1), nil
+		}
+		if i := m.settingsChainIndex(); i >= 0 {
+			m.settingsChain[i].Enabled = !m.settingsChain[i].Enabled
 			return m, nil
 		}
-		if m.settingsCursor == m.settingsAILyricsField() {
-			m.settingsAILyrics = !m.settingsAILyrics
+
+	case " ":
+		if i := m.settingsChainIndex(); i >= 0 {
+			m.settingsChain[i].Enabled = !m.settingsChain[i].Enabled
 			return m, nil
 		}
+
+	case "shift+up", "shift+down":
+		i := m.settingsChainIndex()
+		if i < 0 {
+			return m, nil
+		}
+		j := i - 1
+		if msg.String() == "shift+down" {
+			j = i + 1
+		}
+		if j < 0 || j >= len(m.settingsChain) {
+			return m, nil
+		}
+		m.settingsChain[i], m.settingsChain[j] = m.settingsChain[j], m.settingsChain[i]
+		m.settingsCursor += j - i
+		return m, nil
 	}
 
 	var cmd tea.Cmd
-	switch m.settingsCursor {
-	case 1:
+	switch {
+	case m.settingsCursor == 1:
 		m.settingsModel, cmd = m.settingsModel.Update(msg)
-	case 2:
+	case m.settingsCursor == 2 && m.settingsHasAPIKey():
 		m.settingsAPIKey, cmd = m.settingsAPIKey.Update(msg)
 	}
 	return m, cmd
@@ -381,10 +811,10 @@ func (m Model) handleSettingsKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m Model) focusSettingsField() Model {
 	m.settingsModel.Blur()
 	m.settingsAPIKey.Blur()
-	switch m.settingsCursor {
-	case 1:
+	switch {
+	case m.settingsCursor == 1:
 		m.settingsModel.Focus()
-	case 2:
+	case m.settingsCursor == 2 && m.settingsHasAPIKey():
 		m.settingsAPIKey.Focus()
 	}
 	return m
@@ -396,8 +826,7 @@ func (m Model) handleWindowSizeMsg(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 	m.width = msg.Width
 	m.height = msg.Height
 
-	leftWidth := m.width / 4
-	rightWidth := m.width - leftWidth - 6
+	rightWidth, _ := m.rightColumnWidths()
 
 	if !m.ready {
 		m.viewport.Width = rightWidth
@@ -454,7 +883,7 @@ func (m Model) handlePlaybackPosition(msg playbackPosition) (tea.Model, tea.Cmd)
 		if m.followMode {
 			currentIdx := m.getCurrentLineIndex()
 			if currentIdx >= 0 {
-				centerOffset := currentIdx - (m.viewport.Height / 2)
+				centerOffset := m.lineRow(currentIdx) - (m.viewport.Height / 2)
 				if centerOffset < 0 {
 					centerOffset = 0
 				}
@@ -478,12 +907,18 @@ func (m Model) handleMPRISData(msg mprisData) (tea.Model, tea.Cmd) {
 		m.debugInfo = ""
 		m.mprisArtist = ""
 		m.mprisTitle = ""
+		m.mprisAlbum = ""
+		m.mprisURL = ""
+		m.mprisLength = 0
 		return m, nil
 	}
 
 	m.debugInfo = fmt.Sprintf("%s\n%s", msg.artist, msg.title)
 	m.mprisArtist = msg.artist
 	m.mprisTitle = msg.title
+	m.mprisAlbum = msg.album
+	m.mprisURL = msg.url
+	m.mprisLength = msg.length
 
 	if !m.autoDetectMode {
 		return m, nil
@@ -495,6 +930,7 @@ func (m Model) handleMPRISData(msg mprisData) (tea.Model, tea.Cmd) {
 	}
 
 	m.lastDetectedSong = songKey
+	m = m.startLookup()
 	m.searching = true
 
 	m.artist = ""
@@ -502,11 +938,15 @@ func (m Model) handleMPRISData(msg mprisData) (tea.Model, tea.Cmd) {
 	m.lyrics = ""
 	m.syncedLyrics = nil
 	m.hasSyncedLyrics = false
+	m.source = ""
+	m.sourceModel = ""
+	m.verification = ""
 	m.playbackPosition = 0
 	m.duration = 0
 	m.parsedArtist = ""
 	m.parsedTitle = ""
 	m.ignorePositionUntil = time.Now().Add(2 * time.Second)
+	m, _ = m.resetAnnotations(0)
 
 	cached, err := m.lyricsService.LoadFromCache(msg.artist, msg.title)
 	if err == nil {
@@ -515,6 +955,10 @@ func (m Model) handleMPRISData(msg mprisData) (tea.Model, tea.Cmd) {
 		m.title = cached.Title
 		m.lyrics = cached.Lyrics
 		m.syncedLyrics = cached.SyncedLyrics
+		m.source = cached.Source
+		m.sourceModel = cached.Model
+		m.verification = cached.Verification
+		m.confidence = cached.Confidence
 		m.hasSyncedLyrics = cached.HasSyncedLyrics
 		m.parsedArtist = cached.Artist
 		m.parsedTitle = cached.Title
@@ -522,14 +966,20 @@ func (m Model) handleMPRISData(msg mprisData) (tea.Model, tea.Cmd) {
 		m.offset = cached.Offset
 		m.ignorePositionUntil = time.Now().Add(1 * time.Second)
 
+		m = m.loadTranslation()
+
+		var annCmd tea.Cmd
+		m, annCmd = m.resetAnnotations(cached.GeniusID)
+
 		if m.hasSyncedLyrics {
 			m.viewport.SetContent(m.renderSyncedLyrics())
 		} else {
-			m.viewport.SetContent(cached.Lyrics)
+			m.viewport.SetContent(m.renderPlainLyrics(cached.Lyrics))
 		}
-		return m, tea.Tick(1*time.Second, func(t time.Time) tea.Msg {
+		// keep the model loaded for the next song that isn't cached
+		return m, tea.Batch(annCmd, m.warmParser(), tea.Tick(1*time.Second, func(t time.Time) tea.Msg {
 			return m.getPlaybackPosition()()
-		})
+		}))
 	}
 
 	query := msg.artist + " " + msg.title
@@ -537,31 +987,137 @@ func (m Model) handleMPRISData(msg mprisData) (tea.Model, tea.Cmd) {
 	m.lastMprisArtist = msg.artist
 	m.lastMprisTitle = msg.title
 	m.viewport.SetContent(fmt.Sprintf("New song detected!\n\n%s\n\nFetching lyrics...", query))
-	if m.config.AILyrics {
-		return m, m.fetchAILyrics(query, msg.artist, msg.title)
+
+	if alias, ok := m.aliases.Lookup(query, msg.artist, msg.title); ok {
+		m.parsedArtist = alias.Artist
+		m.parsedTitle = alias.Title
+		return m, tea.Batch(m.fetchLyrics(alias.Artist, alias.Title, msg.artist, msg.title), m.warmParser())
+	}
+
+	// the player's tags usually name the song already; the parser only
+	// gets the query if looking them up finds nothing, so load its model
+	// meanwhile
+	if artist, title, ok := parse.CleanTags(msg.artist, msg.title); ok {
+		m.parsedArtist = artist
+		m.parsedTitle = title
+		if !m.lyricsService.OfflineOnly() && !m.budgetReached() && m.parser.ID() != parse.ProviderHeuristic {
+			m.parseFallback = query
+		}
+		return m, tea.Batch(m.fetchLyrics(artist, title, msg.artist, msg.title), m.warmParser())
+	}
+
+	// no AI to clean up the query offline or over budget, the player's
+	// tags will do
+	if m.lyricsService.OfflineOnly() || m.budgetReached() {
+		m.parsedArtist = msg.artist
+		m.parsedTitle = msg.title
+		return m, m.fetchLyrics(msg.artist, msg.title, msg.artist, msg.title)
 	}
 	return m, m.searchLyricsWithMpris(query, msg.artist, msg.title)
 }
 
+// budgetReached reports whether the daily AI cap is used up.
+func (m Model) budgetReached() bool {
+	return m.ledger != nil && m.ledger.Allow() != nil
+}
+
 func (m Model) handleParsedResult(msg parsedResult) (tea.Model, tea.Cmd) {
+	if msg.gen != m.lookupGen {
+		return m, nil
+	}
 	if msg.err != nil {
 		m.searching = false
 		m.err = msg.err
+		if errors.Is(msg.err, httpclient.ErrOffline) {
+			m.viewport.SetContent(offlineMessage)
+			return m, nil
+		}
 		m.viewport.SetContent(fmt.Sprintf("Parse error: %s", msg.err))
 		return m, nil
 	}
 
 	m.parsedArtist = msg.artist
 	m.parsedTitle = msg.title
+	m.parsedBy = msg.provider
+	// the heuristic's splits aren't worth remembering, it would make them
+	// again
+	if !msg.fromAlias && msg.provider != parse.ProviderHeuristic {
+		alias := parse.Alias{Query: msg.query, Artist: msg.artist, Title: msg.title, Provider: msg.provider}
+		if msg.mprisArtist != "" && msg.mprisTitle != "" {
+			alias.Query, alias.RawArtist, alias.RawTitle = "", msg.mprisArtist, msg.mprisTitle
+		}
+		m.aliases.Set(alias)
+	}
 	m.viewport.SetContent(fmt.Sprintf("Parsed!\nFetching lyrics for:\n%s - %s", msg.artist, msg.title))
 	return m, m.fetchLyrics(msg.artist, msg.title, msg.mprisArtist, msg.mprisTitle)
 }
 
+// handleLyricsProgress shows lyrics as they stream in. They only become
+// final, and cached, once the lookup's searchResult arrives.
+func (m Model) handleLyricsProgress(msg lyricsProgress) (tea.Model, tea.Cmd) {
+	next := waitForLookup(msg.next)
+	if msg.gen != m.lookupGen {
+		return m, next
+	}
+
+	m.artist, m.title = m.parsedArtist, m.parsedTitle
+	if msg.partial.Artist != "" {
+		m.artist = msg.partial.Artist
+	}
+	if msg.partial.Title != "" {
+		m.title = msg.partial.Title
+	}
+	m.lyrics = msg.partial.Lyrics
+	m.syncedLyrics = nil
+	m.hasSyncedLyrics = false
+	m.estimatedTimestamps = false
+	m.source = msg.partial.Source
+	m.sourceModel = ""
+	m.verification = ""
+	m.streaming = true
+	m, _ = m.resetAnnotations(0)
+
+	m.viewport.SetContent(m.renderPlainLyrics(m.lyrics))
+	if m.followMode {
+		m.viewport.GotoBottom()
+	}
+	return m, next
+}
+
+const offlineMessage = "You appear to be offline.\n\nCached lyrics still work; press Ctrl+R to retry once you're back online."
+
 func (m Model) handleSearchResult(msg searchResult) (tea.Model, tea.Cmd) {
+	if msg.gen != m.lookupGen {
+		// superseded by a newer lookup: remember the lyrics for when that
+		// song plays again, but keep the current one on screen
+		if msg.err == nil && msg.mprisArtist != "" && msg.mprisTitle != "" {
+			offset := m.lyricsService.CachedOffset(msg.mprisArtist, msg.mprisTitle)
+			m.lyricsService.SaveToCache(msg.mprisArtist, msg.mprisTitle, msg.song, offset)
+		}
+		return m, nil
+	}
+
+	if msg.err != nil && m.parseFallback != "" && !errors.Is(msg.err, lyrics.ErrQueued) && !errors.Is(msg.err, httpclient.ErrOffline) {
+		query := m.parseFallback
+		m.parseFallback = ""
+		m.streaming = false
+		m.viewport.SetContent(fmt.Sprintf("Nothing found for\n%s - %s\n\nAsking %s...", m.parsedArtist, m.parsedTitle, m.parser.Name()))
+		return m, m.searchLyricsWithMpris(query, msg.mprisArtist, msg.mprisTitle)
+	}
+	m.parseFallback = ""
 	m.searching = false
+	m.streaming = false
 
 	if msg.err != nil {
 		m.err = msg.err
+		if errors.Is(msg.err, lyrics.ErrQueued) {
+			m.viewport.SetContent(fmt.Sprintf("No lyrics available offline for\n%s - %s\n\nThe song is queued and will get lyrics once you're back online.", m.parsedArtist, m.parsedTitle))
+			return m, nil
+		}
+		if errors.Is(msg.err, httpclient.ErrOffline) {
+			m.viewport.SetContent(offlineMessage)
+			return m, nil
+		}
 		m.viewport.SetContent(fmt.Sprintf("Error: %s", msg.err))
 		return m, nil
 	}
@@ -571,44 +1127,101 @@ func (m Model) handleSearchResult(msg searchResult) (tea.Model, tea.Cmd) {
 	m.lyrics = msg.song.Lyrics
 	m.syncedLyrics = msg.song.SyncedLyrics
 	m.hasSyncedLyrics = msg.song.HasSyncedLyrics
+	m.source = msg.song.Source
+	m.sourceModel = msg.song.Model
+	m.verification = msg.song.Verification
+	m.confidence = msg.song.Confidence
 	m.playbackPosition = 0
 	m.offset = 0
 	m.ignorePositionUntil = time.Now().Add(1 * time.Second)
 
+	// a song looked up again keeps the offset set for it
 	if msg.mprisArtist != "" && msg.mprisTitle != "" {
-		m.lyricsService.SaveToCache(msg.mprisArtist, msg.mprisTitle, msg.song, 0)
+		m.offset = m.lyricsService.CachedOffset(msg.mprisArtist, msg.mprisTitle)
+		m.lyricsService.SaveToCache(msg.mprisArtist, msg.mprisTitle, msg.song, m.offset)
+	}
+
+	// check AI lyrics against other sources once they are on screen
+	var verifyCmd tea.Cmd
+	if msg.song.Source == lyrics.ProviderAI && msg.song.Verification == "" && !m.lyricsService.OfflineOnly() {
+		verifyCmd = m.verifyAILyrics(msg.song.Artist, msg.song.Title, msg.mprisArtist, msg.mprisTitle)
+	}
+
+	// AI lyrics come without timing, spread them over the song so follow
+	// mode still works
+	if msg.song.Source == lyrics.ProviderAI && !msg.song.HasSyncedLyrics {
+		m.syncedLyrics = estimateTimestamps(msg.song.Lyrics)
+		m.hasSyncedLyrics = true
+		m.estimatedTimestamps = true
 	}
 
+	m = m.loadTranslation()
+
+	var annCmd tea.Cmd
+	m, annCmd = m.resetAnnotations(msg.song.GeniusID)
+
 	if m.hasSyncedLyrics {
 		m.viewport.SetContent(m.renderSyncedLyrics())
 	} else {
-		m.viewport.SetContent(msg.song.Lyrics)
+		m.viewport.SetContent(m.renderPlainLyrics(msg.song.Lyrics))
 	}
 
-	return m, tea.Tick(1*time.Second, func(t time.Time) tea.Msg {
+	return m, tea.Batch(annCmd, verifyCmd, tea.Tick(1*time.Second, func(t time.Time) tea.Msg {
 		return m.getPlaybackPosition()()
-	})
+	}))
 }
 
-func (m Model) handleAILyricsResult(msg aiLyricsResult) (tea.Model, tea.Cmd) {
-	m.searching = false
-
+func (m Model) handleVerifyResult(msg verifyResult) (tea.Model, tea.Cmd) {
 	if msg.err != nil {
-		m.err = msg.err
-		m.viewport.SetContent(fmt.Sprintf("Error: %s", msg.err))
+		return m, nil
+	}
+	if msg.mprisArtist != "" && msg.mprisTitle != "" {
+		// the offset may have been changed while verifying
+		offset := m.offset
+		if msg.gen != m.lookupGen {
+			offset = m.lyricsService.CachedOffset(msg.mprisArtist, msg.mprisTitle)
+		}
+		m.lyricsService.SaveToCache(msg.mprisArtist, msg.mprisTitle, msg.song, offset)
+	}
+	if msg.gen != m.lookupGen {
 		return m, nil
 	}
 
-	m.artist = msg.artist
-	m.title = msg.title
-	m.parsedArtist = msg.artist
-	m.parsedTitle = msg.title
-	m.lyrics = msg.lyrics
-	m.playbackPosition = 0
-	m.offset = 0
-	m.ignorePositionUntil = time.Now().Add(1 * time.Second)
+	if !msg.replaced {
+		m.verification = msg.song.Verification
+		m.confidence = msg.song.Confidence
+		return m, nil
+	}
+
+	m.lyrics = msg.song.Lyrics
+	m.syncedLyrics = msg.song.SyncedLyrics
+	m.hasSyncedLyrics = msg.song.HasSyncedLyrics
+	m.estimatedTimestamps = false
+	m.source = msg.song.Source
+	m.sourceModel = msg.song.Model
+	m.verification = msg.song.Verification
+	m.confidence = msg.song.Confidence
+	m.notification = fmt.Sprintf("AI lyrics were wrong, replaced with %s's", sourceName(msg.song.Source))
+	if msg.song.Verification == lyrics.Verified {
+		m.notification = fmt.Sprintf("AI lyrics verified, replaced with %s's", sourceName(msg.song.Source))
+	}
+	m.notificationUntil = time.Now().Add(10 * time.Second)
+	m = m.loadTranslation()
 
-	lines := strings.Split(msg.lyrics, "\n")
+	var annCmd tea.Cmd
+	m, annCmd = m.resetAnnotations(msg.song.GeniusID)
+	if m.hasSyncedLyrics {
+		m.viewport.SetContent(m.renderSyncedLyrics())
+	} else {
+		m.viewport.SetContent(m.renderPlainLyrics(m.lyrics))
+	}
+	return m, annCmd
+}
+
+// estimateTimestamps spreads untimed lyrics evenly over an assumed ~3 min
+// song; handlePlaybackPosition rescales them once the real duration is known.
+func estimateTimestamps(text string) []lyrics.Line {
+	lines := strings.Split(text, "\n")
 	var synced []lyrics.Line
 	nonEmpty := 0
 	for _, l := range lines {
@@ -616,7 +1229,6 @@ func (m Model) handleAILyricsResult(msg aiLyricsResult) (tea.Model, tea.Cmd) {
 			nonEmpty++
 		}
 	}
-	// assume ~3 min until we get real duration
 	interval := 180.0 / float64(nonEmptyOrOne(nonEmpty))
 	ts := 0.0
 	for _, l := range lines {
@@ -625,30 +1237,227 @@ func (m Model) handleAILyricsResult(msg aiLyricsResult) (tea.Model, tea.Cmd) {
 			ts += interval
 		}
 	}
-	m.syncedLyrics = synced
-	m.hasSyncedLyrics = true
-	m.estimatedTimestamps = true
+	return synced
+}
 
-	m.viewport.SetContent(m.renderSyncedLyrics())
+// --- offline queue ---
 
-	cacheArtist := msg.mprisArtist
-	cacheTitle := msg.mprisTitle
-	if cacheArtist == "" || cacheTitle == "" {
-		cacheArtist = msg.artist
-		cacheTitle = msg.title
+func (m Model) toggleOfflineOnly() (tea.Model, tea.Cmd) {
+	m.config.OfflineOnly = !m.config.OfflineOnly
+	m.lyricsService.SetOfflineOnly(m.config.OfflineOnly)
+	m.config.Save()
+
+	if m.config.OfflineOnly || m.processingQueue || m.lyricsService.QueuedCount() == 0 {
+		return m, nil
 	}
-	if cacheArtist != "" || cacheTitle != "" {
-		song := &lyrics.Song{
-			Artist: cacheArtist,
-			Title:  cacheTitle,
-			Lyrics: msg.lyrics,
+	m.processingQueue = true
+	return m, m.processQueue()
+}
+
+func (m Model) handleQueueTick() (tea.Model, tea.Cmd) {
+	if m.processingQueue || m.lyricsService.OfflineOnly() || m.lyricsService.QueuedCount() == 0 {
+		return m, tickQueue()
+	}
+	m.processingQueue = true
+	return m, tea.Batch(m.processQueue(), tickQueue())
+}
+
+func (m Model) handleQueueResult(msg queueResult) (tea.Model, tea.Cmd) {
+	m.processingQueue = false
+	if msg.found == 0 {
+		return m, nil
+	}
+
+	m.notification = fmt.Sprintf("%d songs got lyrics", msg.found)
+	if msg.found == 1 {
+		m.notification = "1 song got lyrics"
+	}
+	m.notificationUntil = time.Now().Add(10 * time.Second)
+	return m, nil
+}
+
+// --- annotations panel ---
+
+func (m Model) toggleAnnotations() (tea.Model, tea.Cmd) {
+	m.annotationsOpen = !m.annotationsOpen
+	m.viewport.Width, _ = m.rightColumnWidths()
+	if !m.hasSyncedLyrics && m.lyrics != "" {
+		m.viewport.SetContent(m.renderPlainLyrics(m.lyrics))
+	}
+
+	if m.annotationsOpen && m.geniusID != 0 && m.annotations == nil && !m.annotationsLoading {
+		m.annotationsLoading = true
+		return m, m.fetchAnnotations(m.geniusID, m.lyrics)
+	}
+	return m, nil
+}
+
+// resetAnnotations clears annotations for a newly loaded song and starts
+// fetching the new ones when the panel is open.
+func (m Model) resetAnnotations(geniusID int) (Model, tea.Cmd) {
+	m.geniusID = geniusID
+	m.annotations = nil
+	m.annotationsErr = nil
+	m.annotationsLoading = false
+	m.annotationIdx = 0
+	m.plainLines = nil
+
+	if !m.annotationsOpen || geniusID == 0 {
+		return m, nil
+	}
+	m.annotationsLoading = true
+	return m, m.fetchAnnotations(geniusID, m.lyrics)
+}
+
+func (m Model) handleAnnotationsResult(msg annotationsResult) (tea.Model, tea.Cmd) {
+	if msg.gen != m.lookupGen || msg.geniusID != m.geniusID {
+		return m, nil
+	}
+
+	m.annotationsLoading = false
+	m.annotations = msg.annotations
+	m.annotationsErr = msg.err
+	if m.annotations == nil && msg.err == nil {
+		m.annotations = []lyrics.Annotation{}
+	}
+	if m.hasSyncedLyrics {
+		m.plainLines = lyrics.PlainLines(m.lyrics, m.syncedLyrics)
+	}
+	return m.refreshAnnotatedLyrics(), nil
+}
+
+// refreshAnnotatedLyrics re-renders plain lyrics so the focus marker follows
+// scrolling while the annotations panel is open.
+func (m Model) refreshAnnotatedLyrics() Model {
+	if m.annotationsOpen && !m.hasSyncedLyrics && m.lyrics != "" {
+		m.viewport.SetContent(m.renderPlainLyrics(m.lyrics))
+	}
+	return m
+}
+
+// focusLine is the plain lyrics line annotations are shown for: the one
+// the highlighted synced line shows, or the top visible line.
+func (m Model) focusLine() int {
+	if m.hasSyncedLyrics {
+		if i := m.getCurrentLineIndex(); i >= 0 && i < len(m.plainLines) {
+			return m.plainLines[i]
 		}
-		m.lyricsService.SaveToCache(cacheArtist, cacheTitle, song, 0)
+		return -1
 	}
+	return m.rowLine(m.viewport.YOffset)
+}
 
-	return m, tea.Tick(1*time.Second, func(t time.Time) tea.Msg {
-		return m.getPlaybackPosition()()
-	})
+// --- translation ---
+
+// toggleTranslation shows the translation of the loaded lyrics, asking the
+// AI for one when none is cached, or hides it again.
+func (m Model) toggleTranslation() (tea.Model, tea.Cmd) {
+	if m.showTranslation && (m.translation != nil || m.translating) {
+		m.showTranslation = false
+		return m.refreshLyrics(), nil
+	}
+	m.showTranslation = true
+	lines := m.lyricLines()
+	if m.translation != nil || m.translating || m.searching || m.streaming || len(lines) == 0 {
+		return m.refreshLyrics(), nil
+	}
+
+	translator, ok := m.parser.(parse.Translator)
+	switch {
+	case !ok:
+		m.notification = fmt.Sprintf("%s can't translate lyrics", m.parser.Name())
+	case m.lyricsService.OfflineOnly():
+		m.notification = "Translating needs the AI, which offline mode keeps off"
+	case m.budgetReached():
+		m.notification = "Today's AI budget is used up"
+	default:
+		m.translating = true
+		return m, m.translateLyrics(translator, lines, m.translateLanguage())
+	}
+	m.showTranslation = false
+	m.notificationUntil = time.Now().Add(5 * time.Second)
+	return m, nil
+}
+
+func (m Model) handleTranslationResult(msg translationResult) (tea.Model, tea.Cmd) {
+	if msg.err == nil {
+		for _, key := range msg.keys {
+			m.lyricsService.SaveTranslation(key[0], key[1], msg.language, msg.lines)
+		}
+	}
+	if msg.gen != m.lookupGen {
+		return m, nil
+	}
+
+	m.translating = false
+	if msg.err != nil {
+		m.showTranslation = false
+		m.notification = fmt.Sprintf("Translation failed: %s", msg.err)
+		m.notificationUntil = time.Now().Add(10 * time.Second)
+		return m, nil
+	}
+	m.translation = msg.lines
+	return m.refreshLyrics(), nil
+}
+
+// loadTranslation picks up the cached translation of newly loaded lyrics.
+func (m Model) loadTranslation() Model {
+	m.translation = nil
+	n := len(m.lyricLines())
+	for _, key := range m.songKeys() {
+		if lines := m.lyricsService.Translation(key[0], key[1], m.translateLanguage()); len(lines) == n {
+			m.translation = lines
+			break
+		}
+	}
+	return m
+}
+
+// lyricLines are the loaded lyrics line by line, the lines the translation
+// is aligned with.
+func (m Model) lyricLines() []string {
+	if !m.hasSyncedLyrics {
+		if m.lyrics == "" {
+			return nil
+		}
+		return strings.Split(m.lyrics, "\n")
+	}
+	lines := make([]string, len(m.syncedLyrics))
+	for i, line := range m.syncedLyrics {
+		lines[i] = line.Text
+	}
+	return lines
+}
+
+// songKeys are the artist and title pairs the loaded song may be cached
+// under: the song's own and the player's tags.
+func (m Model) songKeys() [][2]string {
+	var keys [][2]string
+	if m.artist != "" && m.title != "" {
+		keys = append(keys, [2]string{m.artist, m.title})
+	}
+	if m.mprisArtist != "" && m.mprisTitle != "" && (m.mprisArtist != m.artist || m.mprisTitle != m.title) {
+		keys = append(keys, [2]string{m.mprisArtist, m.mprisTitle})
+	}
+	return keys
+}
+
+func (m Model) translateLanguage() string {
+	if m.config.TranslateLanguage != "" {
+		return m.config.TranslateLanguage
+	}
+	return "English"
+}
+
+// refreshLyrics re-renders the loaded lyrics, keeping the scroll position.
+func (m Model) refreshLyrics() Model {
+	switch {
+	case m.hasSyncedLyrics:
+		m.viewport.SetContent(m.renderSyncedLyrics())
+	case m.lyrics != "":
+		m.viewport.SetContent(m.renderPlainLyrics(m.lyrics))
+	}
+	return m
 }
 
 func (m Model) saveOffsetToCache() {
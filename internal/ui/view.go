@@ -2,10 +2,13 @@ package ui
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 
+	"lyrics-tui/internal/lyrics"
 	"lyrics-tui/internal/parse"
 )
 
@@ -23,20 +26,38 @@ func (m Model) View() string {
 	if m.cachedSongsModalOpen {
 		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.renderCachedSongsModal())
 	}
+	if m.debugOpen {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.renderDebugModal())
+	}
 
 	leftWidth := m.width / 4
-	rightWidth := m.width - leftWidth - 6
+	lyricsWidth, panelWidth := m.rightColumnWidths()
 
 	leftColumn := m.renderLeftColumn(leftWidth)
-	lyricsBox := m.renderLyricsBox(rightWidth)
+	lyricsBox := m.renderLyricsBox(lyricsWidth)
 
 	content := lipgloss.JoinHorizontal(lipgloss.Top, leftColumn, lyricsBox)
+	if m.annotationsOpen {
+		content = lipgloss.JoinHorizontal(lipgloss.Top, content, m.renderAnnotationsPanel(panelWidth))
+	}
 
-	help := helpStyle.Render("\n/: search • Ctrl+R: retry • Ctrl+/: cached • Tab: auto-detect • f: follow • +/-: timing • Ctrl+O: settings • Esc: quit")
+	help := helpStyle.Render("\n/: search • Ctrl+R: retry • Ctrl+/: cached • Tab: auto-detect • f: follow • +/-: timing • a: annotations • t: translate • o: offline mode • Ctrl+O: settings • Ctrl+D: debug • Esc: quit")
 
 	return lipgloss.JoinVertical(lipgloss.Left, content, help)
 }
 
+// rightColumnWidths splits the space right of the left column between the
+// lyrics box and, when open, the annotations panel.
+func (m Model) rightColumnWidths() (lyricsWidth, panelWidth int) {
+	leftWidth := m.width / 4
+	rightWidth := m.width - leftWidth - 6
+	if !m.annotationsOpen {
+		return rightWidth, 0
+	}
+	panelWidth = rightWidth / 3
+	return rightWidth - panelWidth - 2, panelWidth
+}
+
 // --- left panel: 3 bordered boxes ---
 
 func (m Model) renderLeftColumn(width int) string {
@@ -62,7 +83,26 @@ func (m Model) renderHeaderBox(width, height int) string {
 	}
 
 	line1 := titleStyle.Render(fmt.Sprintf("♪ Lyrics TUI %s", versionStr))
-	line2 := helpStyle.Render(fmt.Sprintf("  %s (%s)", m.parser.Name(), m.config.Model))
+	if m.lyricsService.OfflineOnly() {
+		line1 += warningStyle.Render(fmt.Sprintf("  ✈ offline mode (%d queued)", m.lyricsService.QueuedCount()))
+	} else if m.httpClient.Offline() {
+		line1 += errorStyle.Render("  ● offline")
+	}
+	if m.notification != "" && time.Now().Before(m.notificationUntil) {
+		line1 += activeStyle.Render("  ✓ " + m.notification)
+	}
+	line2 := helpStyle.Render("  " + m.parser.Name())
+	if m.config.Model != "" && m.parser.DefaultModel() != "" {
+		line2 = helpStyle.Render(fmt.Sprintf("  %s (%s)", m.parser.Name(), m.config.Model))
+	}
+	if fp, ok := m.parser.(*parse.FallbackProvider); ok && m.parsedBy != "" && m.parsedBy != fp.ID() {
+		for _, provider := range fp.Providers() {
+			if provider.ID() == m.parsedBy {
+				line2 += warningStyle.Render(fmt.Sprintf(" → %s answered", provider.Name()))
+				break
+			}
+		}
+	}
 
 	content := lipgloss.JoinVertical(lipgloss.Left, line1, line2)
 
@@ -84,11 +124,30 @@ func (m Model) renderLoadedSongBox(width, height int) string {
 		parts = append(parts, infoStyle.Render("♪ "+m.artist))
 		parts = append(parts, infoStyle.Render("  "+m.title))
 
-		if m.searching {
+		if m.streaming {
+			parts = append(parts, "")
+			parts = append(parts, activeStyle.Render("Writing lyrics..."))
+		} else if m.searching {
 			parts = append(parts, "")
 			parts = append(parts, activeStyle.Render("Searching..."))
 		}
 
+		if m.source != "" {
+			badge := sourceBadge(m.source, m.sourceModel, m.verification, m.confidence, m.hasSyncedLyrics && !m.estimatedTimestamps)
+			if m.source == lyrics.ProviderAI && m.verification != lyrics.Verified {
+				parts = append(parts, warningStyle.Render("  "+badge))
+			} else {
+				parts = append(parts, helpStyle.Render("  "+badge))
+			}
+		}
+
+		switch {
+		case m.translating:
+			parts = append(parts, activeStyle.Render("Translating..."))
+		case m.showTranslation && m.translation != nil:
+			parts = append(parts, helpStyle.Render("Translation: "+m.translateLanguage()))
+		}
+
 		if m.offset != 0 {
 			parts = append(parts, "")
 			parts = append(parts, helpStyle.Render(fmt.Sprintf("Offset: %+.1fs", m.offset)))
@@ -152,30 +211,125 @@ func (m Model) renderNowPlayingBox(width, height int) string {
 func (m Model) renderSearchModal() string {
 	var parts []string
 
-	parts = append(parts, titleStyle.Render("Search"))
+	if m.searchByLyrics {
+		parts = append(parts, helpStyle.Render("Search")+"  "+titleStyle.Render("Lyrics"))
+	} else {
+		parts = append(parts, titleStyle.Render("Search")+"  "+helpStyle.Render("Lyrics"))
+	}
 	parts = append(parts, "")
 	parts = append(parts, m.input.View())
 	parts = append(parts, "")
-	parts = append(parts, helpStyle.Render("Enter: search · Esc: cancel"))
 
+	if !m.searchByLyrics {
+		parts = append(parts, helpStyle.Render("Enter: search · Tab: by lyrics · Esc: cancel"))
+		return m.renderSearchModalBox(parts, 50)
+	}
+
+	if m.snippetQuery != "" {
+		parts = append(parts, m.renderCandidateList()...)
+		parts = append(parts, "")
+	}
+	help := "Enter: search · Tab: by title · Esc: cancel"
+	if len(m.snippetCandidates) > 0 && m.input.Value() == m.snippetQuery {
+		help = "Enter: load · ↑/↓: navigate · Tab: by title · Esc: cancel"
+	}
+	if m.snippetQuery != "" && m.snippetSearching == "" && m.canIdentify() {
+		help += " · Ctrl+G: ask " + m.parser.Name()
+	}
+	parts = append(parts, helpStyle.Render(help))
+	return m.renderSearchModalBox(parts, 60)
+}
+
+func (m Model) renderSearchModalBox(parts []string, width int) string {
 	content := lipgloss.JoinVertical(lipgloss.Left, parts...)
 
 	return lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(mauve).
 		Padding(1, 2).
-		Width(50).
+		Width(width).
 		Render(content)
 }
 
+// renderCandidateList lists the songs a lyric search found with where
+// each was found, and the line that matched for the selected one.
+func (m Model) renderCandidateList() []string {
+	var parts []string
+	candidates := m.snippetCandidates
+
+	if len(candidates) > 0 {
+		maxVisible := 8
+		start := 0
+		if m.snippetCursor >= maxVisible {
+			start = m.snippetCursor - maxVisible + 1
+		}
+		end := start + maxVisible
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+
+		for i := start; i < end; i++ {
+			c := candidates[i]
+			line := fmt.Sprintf("%s - %s", c.Title, c.Artist)
+			if i != m.snippetCursor {
+				parts = append(parts, helpStyle.Render("  "+line+" · "+c.Source))
+				continue
+			}
+			parts = append(parts, activeStyle.Render("> "+line)+helpStyle.Render(" · "+c.Source))
+			if c.Snippet != "" {
+				parts = append(parts, infoStyle.Render(fmt.Sprintf("    “%s”", c.Snippet)))
+			}
+		}
+
+		if len(candidates) > maxVisible {
+			parts = append(parts, helpStyle.Render(fmt.Sprintf("  %d/%d", m.snippetCursor+1, len(candidates))))
+		}
+	}
+
+	switch {
+	case m.snippetSearching != "":
+		parts = append(parts, activeStyle.Render(m.snippetSearching))
+	case m.snippetErr != nil:
+		parts = append(parts, errorStyle.Render(fmt.Sprintf("Error: %s", m.snippetErr)))
+	case len(candidates) == 0:
+		parts = append(parts, helpStyle.Render("No songs found"))
+	}
+	return parts
+}
+
 func (m Model) renderCachedSongsModal() string {
 	var parts []string
 
-	parts = append(parts, titleStyle.Render("Cached Songs"))
+	if m.aliasesOpen {
+		parts = append(parts, helpStyle.Render("Cached Songs")+"  "+titleStyle.Render("Aliases"))
+	} else {
+		parts = append(parts, titleStyle.Render("Cached Songs")+"  "+helpStyle.Render("Aliases"))
+	}
 	parts = append(parts, "")
 	parts = append(parts, m.cachedSongsFilter.View())
+	if !m.aliasesOpen {
+		source := "all"
+		if m.cachedBySource {
+			source = sourceName(m.cachedSource)
+		}
+		parts = append(parts, helpStyle.Render("Source: ")+infoStyle.Render(source))
+		if m.refetchingCache {
+			parts = append(parts, activeStyle.Render("Refetching..."))
+		}
+	}
 	parts = append(parts, "")
 
+	if m.aliasesOpen {
+		parts = append(parts, m.renderAliasList()...)
+		parts = append(parts, "")
+		if m.aliasEditing {
+			parts = append(parts, helpStyle.Render("Enter: save · Esc: cancel"))
+		} else {
+			parts = append(parts, helpStyle.Render("Enter: correct · Del: delete · Tab: songs · Esc: cancel"))
+		}
+		return m.renderCachedModalBox(parts)
+	}
+
 	filtered := m.cachedSongsFiltered
 	if len(filtered) == 0 {
 		if m.cachedSongsFilter.Value() != "" {
@@ -197,10 +351,11 @@ func (m Model) renderCachedSongsModal() string {
 		for i := start; i < end; i++ {
 			entry := filtered[i]
 			line := fmt.Sprintf("%s - %s", entry.Title, entry.Artist)
+			source := helpStyle.Render(" · " + sourceName(entry.Source))
 			if i == m.cachedSongsCursor {
-				parts = append(parts, activeStyle.Render("> "+line))
+				parts = append(parts, activeStyle.Render("> "+line)+source)
 			} else {
-				parts = append(parts, helpStyle.Render("  "+line))
+				parts = append(parts, helpStyle.Render("  "+line)+source)
 			}
 		}
 
@@ -211,8 +366,12 @@ func (m Model) renderCachedSongsModal() string {
 	}
 
 	parts = append(parts, "")
-	parts = append(parts, helpStyle.Render("Enter: load · Esc: cancel · ↑/↓: navigate"))
+	parts = append(parts, helpStyle.Render("Enter: load · Tab: aliases · Ctrl+F: source · Ctrl+R: refetch without AI · Esc: cancel · ↑/↓: navigate"))
 
+	return m.renderCachedModalBox(parts)
+}
+
+func (m Model) renderCachedModalBox(parts []string) string {
 	content := lipgloss.JoinVertical(lipgloss.Left, parts...)
 
 	return lipgloss.NewStyle().
@@ -223,6 +382,102 @@ func (m Model) renderCachedSongsModal() string {
 		Render(content)
 }
 
+// renderAliasList lists what queries and player tags were identified as,
+// with the selected alias's provider and date, or its editor.
+func (m Model) renderAliasList() []string {
+	filtered := m.aliasesFiltered
+	if len(filtered) == 0 {
+		if m.cachedSongsFilter.Value() != "" {
+			return []string{helpStyle.Render("No matches")}
+		}
+		return []string{helpStyle.Render("No aliases yet")}
+	}
+
+	var parts []string
+	maxVisible := 7
+	start := 0
+	if m.cachedSongsCursor >= maxVisible {
+		start = m.cachedSongsCursor - maxVisible + 1
+	}
+	end := start + maxVisible
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+
+	for i := start; i < end; i++ {
+		alias := filtered[i]
+		raw := helpStyle.Render("  " + alias.Raw())
+		song := fmt.Sprintf("    → %s - %s", alias.Artist, alias.Title)
+		if i != m.cachedSongsCursor {
+			parts = append(parts, raw, helpStyle.Render(song))
+			continue
+		}
+		parts = append(parts, activeStyle.Render("> "+alias.Raw()))
+		if m.aliasEditing {
+			parts = append(parts, "    → "+m.aliasEdit.View())
+		} else {
+			parts = append(parts, infoStyle.Render(song))
+		}
+		parts = append(parts, helpStyle.Render(fmt.Sprintf("      %s · %s", alias.Provider, alias.Identified.Format("2006-01-02"))))
+	}
+
+	if len(filtered) > maxVisible {
+		parts = append(parts, "")
+		parts = append(parts, helpStyle.Render(fmt.Sprintf("  %d/%d", m.cachedSongsCursor+1, len(filtered))))
+	}
+	return parts
+}
+
+func (m Model) renderDebugModal() string {
+	width := 70
+	var parts []string
+
+	parts = append(parts, titleStyle.Render("Debug"))
+	parts = append(parts, "")
+
+	parts = append(parts, helpStyle.Render("MPRIS"))
+	if m.debugInfo != "" {
+		parts = append(parts, m.debugInfo)
+	} else {
+		parts = append(parts, helpStyle.Render("nothing detected"))
+	}
+	parts = append(parts, "")
+
+	parts = append(parts, helpStyle.Render("Lyrics chain"))
+	parts = append(parts, strings.Join(m.lyricsService.Chain(), " → "))
+	parts = append(parts, "")
+
+	if m.err != nil {
+		parts = append(parts, helpStyle.Render("Last error"))
+		parts = append(parts, errorStyle.Render(m.err.Error()))
+		parts = append(parts, "")
+	}
+
+	diagnostics := m.lyricsService.Diagnostics()
+	ids := make([]string, 0, len(diagnostics))
+	for id := range diagnostics {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		parts = append(parts, helpStyle.Render(id+" stderr"))
+		parts = append(parts, diagnostics[id])
+		parts = append(parts, "")
+	}
+
+	parts = append(parts, helpStyle.Render("Esc: close"))
+
+	content := lipgloss.JoinVertical(lipgloss.Left, parts...)
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(mauve).
+		Padding(1, 2).
+		Width(width).
+		MaxHeight(m.height).
+		Render(content)
+}
+
 func (m Model) renderSettingsModal() string {
 	width := 50
 	var parts []string
@@ -245,9 +500,27 @@ func (m Model) renderSettingsModal() string {
 	} else {
 		parts = append(parts, "  Model       "+m.settingsModel.View())
 	}
+	switch {
+	case m.settingsModelsLoading:
+		parts = append(parts, helpStyle.Render("                listing models..."))
+	case m.settingsModelsErr != nil:
+		parts = append(parts, helpStyle.Render("                couldn't list models"))
+	case len(m.settingsModels) > 0:
+		hint := helpStyle.Render(fmt.Sprintf("◂ ▸ %d models", len(m.settingsModels))) + warningStyle.Render(" • not available")
+		for _, model := range m.settingsModels {
+			if model.Name != m.settingsModel.Value() {
+				continue
+			}
+			hint = helpStyle.Render(fmt.Sprintf("◂ ▸ %d models available", len(m.settingsModels)))
+			if model.Size > 0 {
+				hint = helpStyle.Render(fmt.Sprintf("◂ ▸ %d models • %s", len(m.settingsModels), formatSize(model.Size)))
+			}
+		}
+		parts = append(parts, "                "+hint)
+	}
 	parts = append(parts, "")
 
-	if providerID != parse.ProviderOllama {
+	if m.settingsHasAPIKey() {
 		if m.settingsCursor == 2 {
 			parts = append(parts, activeStyle.Render("> ")+"API Key     "+m.settingsAPIKey.View())
 		} else {
@@ -260,6 +533,8 @@ func (m Model) renderSettingsModal() string {
 			envVar = "OPENAI_API_KEY"
 		case parse.ProviderGemini:
 			envVar = "GEMINI_API_KEY"
+		case parse.ProviderAnthropic:
+			envVar = "ANTHROPIC_API_KEY"
 		}
 		if envVar != "" {
 			parts = append(parts, helpStyle.Render("                default: "+envVar))
@@ -267,15 +542,21 @@ func (m Model) renderSettingsModal() string {
 		parts = append(parts, "")
 	}
 
-	aiLyricsField := m.settingsAILyricsField()
-	aiLyricsVal := "OFF"
-	if m.settingsAILyrics {
-		aiLyricsVal = "ON"
-	}
-	if m.settingsCursor == aiLyricsField {
-		parts = append(parts, activeStyle.Render("> ")+"AI Lyrics   "+infoStyle.Render("◂ "+aiLyricsVal+" ▸"))
-	} else {
-		parts = append(parts, "  AI Lyrics   "+infoStyle.Render("  "+aiLyricsVal))
+	parts = append(parts, m.renderUsage()...)
+	parts = append(parts, "")
+
+	parts = append(parts, "  Lyrics sources")
+	for i, entry := range m.settingsChain {
+		check := "[ ]"
+		if entry.Enabled {
+			check = "[x]"
+		}
+		label := fmt.Sprintf("%s %d. %s", check, i+1, entry.ID)
+		if m.settingsCursor == m.settingsChainStart()+i {
+			parts = append(parts, activeStyle.Render("  > ")+infoStyle.Render(label))
+		} else {
+			parts = append(parts, "    "+helpStyle.Render(label))
+		}
 	}
 	parts = append(parts, "")
 
@@ -291,6 +572,7 @@ func (m Model) renderSettingsModal() string {
 
 	parts = append(parts, helpStyle.Render("  Enter: save · Esc: cancel"))
 	parts = append(parts, helpStyle.Render("  ◂/▸: change provider · Tab: next field"))
+	parts = append(parts, helpStyle.Render("  Space: toggle source · Shift+↑/↓: reorder"))
 
 	content := lipgloss.JoinVertical(lipgloss.Left, parts...)
 
@@ -313,6 +595,51 @@ func (m Model) renderLyricsBox(width int) string {
 		Render(m.viewport.View())
 }
 
+func (m Model) renderAnnotationsPanel(width int) string {
+	var parts []string
+
+	parts = append(parts, helpStyle.Render("Annotations"))
+	parts = append(parts, "")
+
+	switch {
+	case m.geniusID == 0:
+		parts = append(parts, helpStyle.Render("Only available for lyrics from Genius"))
+	case m.annotationsLoading:
+		parts = append(parts, activeStyle.Render("Loading..."))
+	case m.annotationsErr != nil:
+		parts = append(parts, errorStyle.Render(fmt.Sprintf("Error: %s", m.annotationsErr)))
+	default:
+		line := m.focusLine()
+		current := lyrics.AnnotationsForLine(m.annotations, line)
+		if len(current) == 0 {
+			parts = append(parts, helpStyle.Render(fmt.Sprintf("No annotation for this line (%d in song)", len(m.annotations))))
+			break
+		}
+
+		idx := 0
+		if line == m.annotationLine {
+			idx = m.annotationIdx % len(current)
+		}
+		ann := current[idx]
+
+		parts = append(parts, helpStyle.Render(fmt.Sprintf("%d/%d · n: next", idx+1, len(current))))
+		parts = append(parts, "")
+		parts = append(parts, sectionStyle.Render(ann.Fragment))
+		parts = append(parts, "")
+		parts = append(parts, ann.Body)
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, parts...)
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lavender).
+		Width(width).
+		Height(m.height - 4).
+		MaxHeight(m.height - 2).
+		Render(content)
+}
+
 func (m Model) renderProgressBar(width int) string {
 	if m.duration == 0 {
 		barWidth := width - 14
@@ -350,11 +677,11 @@ func (m Model) renderSyncedLyrics() string {
 
 	if !m.followMode {
 		grayStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#9399b2"))
-		for _, line := range m.syncedLyrics {
+		for i, line := range m.syncedLyrics {
 			if line.Text == "" {
 				rendered = append(rendered, "")
 			} else {
-				rendered = append(rendered, grayStyle.Render("  "+line.Text))
+				rendered = append(rendered, m.renderLyricLine(i, grayStyle.Render("  "), line.Text, grayStyle))
 			}
 		}
 		return strings.Join(rendered, "\n")
@@ -369,15 +696,123 @@ func (m Model) renderSyncedLyrics() string {
 		if line.Text == "" {
 			rendered = append(rendered, "")
 		} else if i == currentIdx {
-			rendered = append(rendered, normalStyle.Render("► "+line.Text))
+			rendered = append(rendered, m.renderLyricLine(i, normalStyle.Render("► "), line.Text, normalStyle))
 		} else {
-			rendered = append(rendered, dimmedStyle.Render("  "+line.Text))
+			rendered = append(rendered, m.renderLyricLine(i, dimmedStyle.Render("  "), line.Text, dimmedStyle))
 		}
 	}
 
 	return strings.Join(rendered, "\n")
 }
 
+// renderPlainLyrics styles section markers like "[Chorus]" so they stand
+// apart from the lyrics themselves. With the annotations panel open,
+// annotated lines are underlined and the focus line is marked.
+func (m Model) renderPlainLyrics(text string) string {
+	lines := strings.Split(text, "\n")
+	focus := m.focusLine()
+	for i, line := range lines {
+		style := lipgloss.NewStyle()
+		switch {
+		case lyrics.IsSectionHeader(strings.TrimSpace(line)):
+			style = sectionStyle
+		case m.annotationsOpen && len(lyrics.AnnotationsForLine(m.annotations, i)) > 0:
+			style = annotatedStyle
+		}
+		marker := ""
+		if m.annotationsOpen {
+			if i == focus {
+				marker = "► "
+			} else {
+				marker = "  "
+			}
+		}
+		lines[i] = m.renderLyricLine(i, marker, line, style)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// minTranslationColumn is the narrowest column lyrics and their translation
+// are shown side by side in; narrower lyrics boxes interleave them.
+const minTranslationColumn = 36
+
+// translated reports whether line i is shown with a translation.
+func (m Model) translated(i int) bool {
+	return m.showTranslation && i < len(m.translation) && m.translation[i] != ""
+}
+
+// sideBySide reports whether translations go next to their lines rather
+// than under them.
+func (m Model) sideBySide() bool {
+	return m.viewport.Width >= 2*minTranslationColumn+2
+}
+
+// renderLyricLine renders lyrics line i behind marker, along with its
+// translation in the same style so the current-line highlight covers both.
+func (m Model) renderLyricLine(i int, marker, text string, style lipgloss.Style) string {
+	if !m.translated(i) {
+		return marker + style.Render(text)
+	}
+	indent := strings.Repeat(" ", lipgloss.Width(marker))
+	if m.sideBySide() {
+		column := (m.viewport.Width - 2) / 2
+		return marker + style.Render(fitWidth(text, column-len(indent))) + "  " + indent + style.Render(fitWidth(m.translation[i], column-len(indent)))
+	}
+	return marker + style.Render(text) + "\n" + indent + style.Copy().Italic(true).Render(m.translation[i])
+}
+
+// lineRow is the viewport row lyrics line i is shown on, interleaved
+// translations taking a row of their own.
+func (m Model) lineRow(i int) int {
+	if !m.showTranslation || m.sideBySide() {
+		return i
+	}
+	row := i
+	for j := 0; j < i; j++ {
+		if m.translated(j) {
+			row++
+		}
+	}
+	return row
+}
+
+// rowLine is the lyrics line shown on a viewport row, the inverse of
+// lineRow.
+func (m Model) rowLine(row int) int {
+	if !m.showTranslation || m.sideBySide() {
+		return row
+	}
+	line := 0
+	for r := 0; ; line++ {
+		next := r + 1
+		if m.translated(line) {
+			next++
+		}
+		if row < next {
+			return line
+		}
+		r = next
+	}
+}
+
+// fitWidth pads or cuts s to exactly width cells.
+func fitWidth(s string, width int) string {
+	if w := lipgloss.Width(s); w <= width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	var sb strings.Builder
+	w := 0
+	for _, r := range s {
+		rw := lipgloss.Width(string(r))
+		if w+rw > width-1 {
+			break
+		}
+		sb.WriteRune(r)
+		w += rw
+	}
+	return sb.String() + "…" + strings.Repeat(" ", width-1-w)
+}
+
 func (m Model) getCurrentLineIndex() int {
 	if len(m.syncedLyrics) == 0 {
 		return -1
@@ -398,3 +833,100 @@ func formatTime(seconds float64) string {
 	secs := int(seconds) % 60
 	return fmt.Sprintf("%d:%02d", mins, secs)
 }
+
+// renderUsage shows today's AI usage against the daily caps, then each
+// provider's share.
+func (m Model) renderUsage() []string {
+	if m.ledger == nil {
+		return nil
+	}
+	total := m.ledger.Total()
+	tokenCap, callCap := m.ledger.Caps()
+
+	calls := fmt.Sprintf("%d calls", total.Calls)
+	if callCap > 0 {
+		calls = fmt.Sprintf("%d/%d calls", total.Calls, callCap)
+	}
+	tokens := formatCount(total.Tokens()) + " tokens"
+	if tokenCap > 0 {
+		tokens = fmt.Sprintf("%s/%s tokens", formatCount(total.Tokens()), formatCount(tokenCap))
+	}
+	line := "  AI today    " + infoStyle.Render(calls+" • "+tokens)
+	if m.budgetReached() {
+		line += warningStyle.Render(" • cap reached")
+	}
+	lines := []string{line}
+
+	today := m.ledger.Today()
+	for _, id := range parse.AllProviders {
+		if usage, ok := today[id]; ok {
+			lines = append(lines, helpStyle.Render(fmt.Sprintf("                %s %d • %s", parse.ProviderName(id), usage.Calls, formatCount(usage.Tokens()))))
+		}
+	}
+	return lines
+}
+
+// sourceName names a lyrics source for display.
+func sourceName(source string) string {
+	switch source {
+	case lyrics.ProviderLRCLIB:
+		return "LRCLIB"
+	case lyrics.ProviderGenius:
+		return "Genius"
+	case lyrics.ProviderLocalLRC:
+		return "Local"
+	case lyrics.ProviderTags:
+		return "Tags"
+	case lyrics.ProviderAI:
+		return "AI"
+	case lyrics.SourceImport:
+		return "Imported"
+	case "":
+		return "unknown"
+	default:
+		return source
+	}
+}
+
+// sourceBadge tells where lyrics came from and how far to trust them, such
+// as "LRCLIB synced" or "AI (gemini-3) – unverified".
+func sourceBadge(source, model, verification string, confidence float64, synced bool) string {
+	if source == lyrics.ProviderAI {
+		name := "AI"
+		if model != "" {
+			name = fmt.Sprintf("AI (%s)", model)
+		}
+		if verification == lyrics.Verified {
+			return fmt.Sprintf("%s – verified %.0f%%", name, confidence*100)
+		}
+		return name + " – unverified"
+	}
+	if synced {
+		return sourceName(source) + " synced"
+	}
+	return sourceName(source) + " plain"
+}
+
+// formatCount shortens large counts, such as 12.3k.
+func formatCount(n int) string {
+	switch {
+	case n >= 1000000:
+		return fmt.Sprintf("%.1fM", float64(n)/1000000)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// formatSize renders a byte count the way model sizes are usually quoted.
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.0f MB", float64(bytes)/(1<<20))
+	default:
+		return fmt.Sprintf("%d B", bytes)
+	}
+}
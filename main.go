@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/joho/godotenv"
 
 	"lyrics-tui/internal/config"
+	"lyrics-tui/internal/httpclient"
 	"lyrics-tui/internal/lyrics"
 	"lyrics-tui/internal/parse"
 	"lyrics-tui/internal/player"
@@ -31,20 +36,47 @@ func main() {
 	}
 	cacheDir := filepath.Join(homeDir, ".config", "lyrics", "cached_songs")
 
-	lrclibProvider := lyrics.NewLRCLIBProvider()
-	geniusProvider := lyrics.NewGeniusProvider(geniusToken)
-	cache := lyrics.NewCache(cacheDir)
-	lyricsService := lyrics.NewService(lrclibProvider, geniusProvider, cache)
-
-	mprisPlayer := player.NewMPRISPlayer()
+	client := httpclient.New(httpclient.Options{
+		Timeout:    time.Duration(cfg.HTTPTimeout) * time.Second,
+		UserAgent:  fmt.Sprintf("lyrics-tui/%s (https://github.com/osszoi/lyrics-tui)", Version),
+		RateLimits: rateLimits(cfg),
+	})
 
-	parser, err := parse.NewProviderFromConfig(cfg)
+	ledger := parse.NewLedger(filepath.Join(homeDir, ".config", "lyrics", "usage.json"), cfg.AIDailyTokens, cfg.AIDailyCalls)
+	parser, err := parse.NewProviderFromConfig(cfg, client.Client, ledger)
 	if err != nil {
 		fmt.Printf("Error creating parser: %v\n", err)
 		os.Exit(1)
 	}
 
-	model := ui.NewModel(lyricsService, mprisPlayer, parser, cfg, Version)
+	registry := lyrics.NewRegistry()
+	registry.Register(lyrics.ProviderLocalLRC, lyrics.NewLocalLRCProvider(cfg.LocalLyricsDir))
+	registry.Register(lyrics.ProviderTags, lyrics.NewTagsProvider())
+	registry.Register(lyrics.ProviderLRCLIB, lyrics.NewLRCLIBProvider(cfg.Endpoint(config.EndpointLRCLIB), client.Client))
+	registry.Register(lyrics.ProviderGenius, lyrics.NewGeniusProvider(geniusToken, cfg.Endpoint(config.EndpointGenius), client.Client))
+	registry.Register(lyrics.ProviderAI, lyrics.NewAIProvider(parser))
+	registerCustomProviders(registry, cfg, client.Client)
+
+	cache := lyrics.NewCache(cacheDir)
+	queue := lyrics.NewQueue(filepath.Join(homeDir, ".config", "lyrics", "queue.json"))
+	lyricsService := lyrics.NewService(registry, cfg.EnabledProviders(), cache, queue)
+	lyricsService.SetOfflineOnly(cfg.OfflineOnly)
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			runImport(lyricsService, os.Args[2:])
+			return
+		case "check-provider":
+			runCheckProvider(os.Args[2:])
+			return
+		}
+	}
+
+	mprisPlayer := player.NewMPRISPlayer()
+
+	aliases := parse.NewAliases(filepath.Join(homeDir, ".config", "lyrics", "aliases.json"))
+	model := ui.NewModel(lyricsService, mprisPlayer, parser, aliases, ledger, client, cfg, Version)
 
 	p := tea.NewProgram(
 		model,
@@ -57,3 +89,82 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// rateLimits applies the configured per-host limits over the defaults.
+func rateLimits(cfg *config.Config) map[string]time.Duration {
+	limits := map[string]time.Duration{}
+	for host, interval := range httpclient.DefaultRateLimits {
+		limits[host] = interval
+	}
+	for host, ms := range cfg.RateLimits {
+		if ms == 0 {
+			delete(limits, host)
+			continue
+		}
+		limits[host] = time.Duration(ms) * time.Millisecond
+	}
+	return limits
+}
+
+// registerCustomProviders adds the [providers.<id>] tables from the config
+// to the registry.
+func registerCustomProviders(registry *lyrics.Registry, cfg *config.Config, client *http.Client) {
+	for _, cp := range cfg.CustomProviders {
+		timeout := time.Duration(cp.Timeout) * time.Second
+		switch cp.Type {
+		case "exec":
+			registry.Register(cp.ID, lyrics.NewExecProvider(cp.Command, cp.Args, timeout))
+		case "http":
+			registry.Register(cp.ID, lyrics.NewHTTPJSONProvider(cp.URL, cp.Headers, cp.Synced, cp.Plain, client))
+		default:
+			fmt.Printf("Warning: provider %q has unknown type %q, skipping\n", cp.ID, cp.Type)
+		}
+	}
+}
+
+// runCheckProvider handles `lyrics-tui check-provider <command> [args...]`,
+// running an exec provider once and reporting protocol violations.
+func runCheckProvider(args []string) {
+	os.Exit(checkProvider(os.Stdout, args))
+}
+
+// checkProvider reports on the exec provider given by args to w and
+// returns the exit status.
+func checkProvider(w io.Writer, args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintln(w, "Usage: lyrics-tui check-provider <command> [args...]")
+		return 1
+	}
+
+	track := lyrics.Track{
+		Artist:   "Queen",
+		Title:    "Bohemian Rhapsody",
+		Album:    "A Night at the Opera",
+		Duration: 354,
+	}
+	problems := lyrics.CheckExecProvider(context.Background(), args[0], args[1:], track)
+	if len(problems) > 0 {
+		fmt.Fprintf(w, "%s does not conform:\n", args[0])
+		for _, problem := range problems {
+			fmt.Fprintf(w, "  - %s\n", problem)
+		}
+		return 1
+	}
+	fmt.Fprintf(w, "%s conforms to the exec provider protocol\n", args[0])
+	return 0
+}
+
+// runImport handles `lyrics-tui import <file> <artist> <title>`.
+func runImport(lyricsService *lyrics.Service, args []string) {
+	if len(args) != 3 {
+		fmt.Println("Usage: lyrics-tui import <file.lrc|file.ttml|file.vtt> <artist> <title>")
+		os.Exit(1)
+	}
+
+	song, err := lyricsService.ImportFile(args[0], args[1], args[2])
+	if err != nil {
+		fmt.Printf("Error importing lyrics: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Imported %d lines for %s - %s\n", len(song.SyncedLyrics), song.Artist, song.Title)
+}
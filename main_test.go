@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"lyrics-tui/internal/config"
+	"lyrics-tui/internal/httpclient"
+)
+
+func TestCheckProviderCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("script providers need a POSIX shell")
+	}
+
+	tests := []struct {
+		name     string
+		script   string
+		wantCode int
+		wantOut  string
+	}{
+		{
+			name:     "conforming",
+			script:   `cat > /dev/null; echo '{"lines": [{"time": 0.5, "text": "Is this the real life?"}]}'`,
+			wantCode: 0,
+			wantOut:  "conforms to the exec provider protocol",
+		},
+		{
+			name:     "failing",
+			script:   `cat > /dev/null; echo 'no archive' >&2; exit 1`,
+			wantCode: 1,
+			wantOut:  "  - stderr: no archive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := filepath.Join(t.TempDir(), "provider.sh")
+			if err := os.WriteFile(script, []byte("#!/bin/sh\n"+tt.script+"\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			if code := checkProvider(&out, []string{script}); code != tt.wantCode {
+				t.Errorf("checkProvider() = %d, want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output %q does not contain %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+
+	var out strings.Builder
+	if code := checkProvider(&out, nil); code != 1 || !strings.Contains(out.String(), "Usage:") {
+		t.Errorf("checkProvider(nil) = %d, %q; want usage", code, out.String())
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.RateLimits = map[string]int{"lyrics.example.org": 250, "lrclib.net": 0, "genius.com": 1000}
+
+	limits := rateLimits(cfg)
+	if got := limits["lyrics.example.org"]; got != 250*time.Millisecond {
+		t.Errorf("mirror limit = %v, want 250ms", got)
+	}
+	if _, ok := limits["lrclib.net"]; ok {
+		t.Error("lrclib.net limit not lifted")
+	}
+	if got := limits["genius.com"]; got != time.Second {
+		t.Errorf("genius.com limit = %v, want the configured 1s", got)
+	}
+	if got := limits["api.genius.com"]; got != httpclient.DefaultRateLimits["api.genius.com"] {
+		t.Errorf("api.genius.com limit = %v, want the default", got)
+	}
+	if _, ok := httpclient.DefaultRateLimits["lrclib.net"]; !ok {
+		t.Error("defaults were modified")
+	}
+}